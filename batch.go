@@ -0,0 +1,120 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BatchJob is a single render request executed by Batch or RunBatch.
+type BatchJob struct {
+	// ID is an optional caller-defined identifier echoed in the result.
+	ID      string
+	Type    RequestType
+	URL     string
+	Options RequestOptions
+}
+
+// BatchResult is the outcome of a BatchJob. Exactly one of Data, Content or
+// Metadata is set on success, depending on the job type.
+type BatchResult struct {
+	// Index is the position of the job in the input, starting at 0.
+	Index int
+	Job   BatchJob
+	// Data holds the body of image, PDF and animated jobs.
+	Data     []byte
+	Content  *ContentResponse
+	Metadata *MetadataResponse
+	Err      error
+	Duration time.Duration
+}
+
+// DefaultBatchConcurrency is used when a batch is started with a
+// concurrency below 1.
+const DefaultBatchConcurrency = 4
+
+// Batch runs jobs with at most concurrency requests in flight and returns a
+// channel that yields one result per job in order of completion. The channel
+// is closed once every job has been reported, so callers must drain it.
+// Jobs that have not started when ctx is canceled are reported with ctx's
+// error without being sent.
+func (c *Capture) Batch(ctx context.Context, jobs []BatchJob, concurrency int) <-chan BatchResult {
+	queue := make(chan BatchJob)
+	go func() {
+		defer close(queue)
+		for _, job := range jobs {
+			queue <- job
+		}
+	}()
+	return c.RunBatch(ctx, queue, concurrency)
+}
+
+// RunBatch is like Batch but reads jobs from a channel, which lets callers
+// stream an unbounded number of jobs. The result channel is closed after
+// jobs is closed and every received job has been reported.
+func (c *Capture) RunBatch(ctx context.Context, jobs <-chan BatchJob, concurrency int) <-chan BatchResult {
+	if concurrency < 1 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	type indexedJob struct {
+		index int
+		job   BatchJob
+	}
+
+	indexed := make(chan indexedJob)
+	results := make(chan BatchResult, concurrency)
+
+	go func() {
+		defer close(indexed)
+		index := 0
+		for job := range jobs {
+			indexed <- indexedJob{index: index, job: job}
+			index++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range indexed {
+				result := c.runBatchJob(ctx, item.job)
+				result.Index = item.index
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+func (c *Capture) runBatchJob(ctx context.Context, job BatchJob) BatchResult {
+	result := BatchResult{Job: job}
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+
+	start := time.Now()
+	switch job.Type {
+	case RequestTypeImage, RequestTypePDF, RequestTypeAnimated:
+		result.Data, result.Err = c.fetchBytes(ctx, job.Type, job.URL, job.Options)
+	case RequestTypeContent:
+		result.Content, result.Err = c.FetchContentContext(ctx, job.URL, job.Options)
+	case RequestTypeMetadata:
+		result.Metadata, result.Err = c.FetchMetadataContext(ctx, job.URL, job.Options)
+	default:
+		result.Err = fmt.Errorf("unsupported request type: %q", job.Type)
+	}
+	result.Duration = time.Since(start)
+
+	return result
+}
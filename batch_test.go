@@ -0,0 +1,108 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/content"):
+			_, _ = w.Write([]byte(`{"success":true,"markdown":"# Hello"}`))
+		case strings.HasSuffix(r.URL.Path, "/metadata"):
+			_, _ = w.Write([]byte(`{"success":true,"metadata":{"title":"Hello"}}`))
+		case r.URL.Query().Get("url") == "https://fail.example.com":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			_, _ = w.Write([]byte("binary"))
+		}
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret")
+	c.APIURL = server.URL
+
+	jobs := []BatchJob{
+		{ID: "a", Type: RequestTypeImage, URL: "https://a.example.com"},
+		{ID: "b", Type: RequestTypePDF, URL: "https://b.example.com"},
+		{ID: "c", Type: RequestTypeContent, URL: "https://c.example.com"},
+		{ID: "d", Type: RequestTypeMetadata, URL: "https://d.example.com"},
+		{ID: "e", Type: RequestTypeImage, URL: "https://fail.example.com"},
+		{ID: "f", Type: "bogus", URL: "https://f.example.com"},
+	}
+
+	results := map[string]BatchResult{}
+	for result := range c.Batch(context.Background(), jobs, 2) {
+		results[result.Job.ID] = result
+	}
+
+	if len(results) != len(jobs) {
+		t.Fatalf("got %d results, want %d", len(results), len(jobs))
+	}
+	if maxInFlight > 2 {
+		t.Fatalf("expected at most 2 concurrent requests, saw %d", maxInFlight)
+	}
+	for i, job := range jobs {
+		if results[job.ID].Index != i {
+			t.Errorf("result %s index = %d, want %d", job.ID, results[job.ID].Index, i)
+		}
+	}
+	if string(results["a"].Data) != "binary" || results["a"].Err != nil {
+		t.Errorf("unexpected image result: %#v", results["a"])
+	}
+	if results["c"].Content == nil || results["c"].Content.Markdown != "# Hello" {
+		t.Errorf("unexpected content result: %#v", results["c"])
+	}
+	if results["d"].Metadata == nil || results["d"].Metadata.Metadata["title"] != "Hello" {
+		t.Errorf("unexpected metadata result: %#v", results["d"])
+	}
+	var apiErr *CaptureAPIError
+	if !errors.As(results["e"].Err, &apiErr) {
+		t.Errorf("expected CaptureAPIError for failing job, got %v", results["e"].Err)
+	}
+	if results["f"].Err == nil {
+		t.Error("expected error for unsupported request type")
+	}
+}
+
+func TestBatchCanceled(t *testing.T) {
+	c := New("test_key", "test_secret")
+	c.APIURL = "http://127.0.0.1:0"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	jobs := make([]BatchJob, 5)
+	for i := range jobs {
+		jobs[i] = BatchJob{Type: RequestTypeImage, URL: "https://example.com"}
+	}
+
+	count := 0
+	for result := range c.Batch(ctx, jobs, 2) {
+		count++
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", result.Err)
+		}
+	}
+	if count != len(jobs) {
+		t.Fatalf("got %d results, want %d", count, len(jobs))
+	}
+}
@@ -0,0 +1,340 @@
+package capture
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheMaxBytes bounds the size of caches created through WithCache.
+const DefaultCacheMaxBytes int64 = 1 << 30
+
+const (
+	cacheBodySuffix = ".body"
+	cacheMetaSuffix = ".json"
+)
+
+// DiskCache stores successful render responses on disk. Entries are keyed by
+// the signed request URL, which is deterministic for a key and option set,
+// so identical requests are served locally until the entry expires.
+type DiskCache struct {
+	Dir string
+	// TTL is how long an entry is served after it was stored. Zero means
+	// entries never expire.
+	TTL time.Duration
+	// MaxBytes bounds the total size of cached bodies. When a new entry
+	// pushes the cache over the limit, expired and then oldest entries are
+	// evicted. Zero disables the limit.
+	MaxBytes int64
+
+	mu    sync.Mutex
+	size  int64
+	sized bool
+}
+
+// CacheEntry describes a cached response.
+type CacheEntry struct {
+	Key         string      `json:"key"`
+	RequestType RequestType `json:"requestType"`
+	URL         string      `json:"url"`
+	Size        int64       `json:"size"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	Expired     bool        `json:"expired"`
+}
+
+type cacheMeta struct {
+	RequestType RequestType `json:"requestType"`
+	URL         string      `json:"url"`
+	Size        int64       `json:"size"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
+
+func NewDiskCache(dir string, ttl time.Duration) *DiskCache {
+	return &DiskCache{Dir: dir, TTL: ttl, MaxBytes: DefaultCacheMaxBytes}
+}
+
+// WithCache serves Fetch* calls from an on-disk cache in dir. Requests with
+// the "fresh" option set bypass cached entries but still refresh the entry
+// of the same request without "fresh".
+func WithCache(dir string, ttl time.Duration) Option {
+	return func(c *Capture) {
+		c.Cache = NewDiskCache(dir, ttl)
+	}
+}
+
+func cacheKey(signedURL string) string {
+	sum := sha256.Sum256([]byte(signedURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// isCacheKey reports whether name is a key produced by cacheKey, so files
+// the cache did not write are never listed or removed.
+func isCacheKey(name string) bool {
+	if len(name) != sha256.Size*2 {
+		return false
+	}
+	for _, r := range name {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *DiskCache) path(key, suffix string) string {
+	return filepath.Join(d.Dir, key+suffix)
+}
+
+func (d *DiskCache) expired(createdAt, now time.Time) bool {
+	return d.TTL > 0 && now.Sub(createdAt) > d.TTL
+}
+
+// open returns the cached body for signedURL if a fresh entry exists.
+func (d *DiskCache) open(signedURL string) (io.ReadCloser, bool) {
+	key := cacheKey(signedURL)
+	meta, err := d.readMeta(key)
+	if err != nil || d.expired(meta.CreatedAt, time.Now()) {
+		return nil, false
+	}
+
+	f, err := os.Open(d.path(key, cacheBodySuffix))
+	if err != nil {
+		return nil, false
+	}
+	return f, true
+}
+
+func (d *DiskCache) readMeta(key string) (*cacheMeta, error) {
+	data, err := os.ReadFile(d.path(key, cacheMetaSuffix))
+	if err != nil {
+		return nil, err
+	}
+	var meta cacheMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// tee wraps body so that a fully read response is stored under signedURL.
+// Caching is best effort: any failure only skips storing the entry.
+func (d *DiskCache) tee(signedURL string, requestType RequestType, targetURL string, body io.ReadCloser) io.ReadCloser {
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return body
+	}
+	tmp, err := os.CreateTemp(d.Dir, ".tmp-*")
+	if err != nil {
+		return body
+	}
+	return &cacheWriter{
+		cache: d,
+		body:  body,
+		tmp:   tmp,
+		key:   cacheKey(signedURL),
+		meta:  cacheMeta{RequestType: requestType, URL: targetURL},
+	}
+}
+
+type cacheWriter struct {
+	cache  *DiskCache
+	body   io.ReadCloser
+	tmp    *os.File
+	key    string
+	meta   cacheMeta
+	failed bool
+	done   bool
+}
+
+func (w *cacheWriter) Read(p []byte) (int, error) {
+	n, err := w.body.Read(p)
+	if n > 0 && !w.failed {
+		if _, werr := w.tmp.Write(p[:n]); werr != nil {
+			w.failed = true
+		}
+		w.meta.Size += int64(n)
+	}
+	if err == io.EOF && !w.done {
+		w.done = true
+		w.commit()
+	}
+	return n, err
+}
+
+func (w *cacheWriter) Close() error {
+	if !w.done {
+		w.done = true
+		w.tmp.Close()
+		os.Remove(w.tmp.Name())
+	}
+	return w.body.Close()
+}
+
+func (w *cacheWriter) commit() {
+	tmpName := w.tmp.Name()
+	if err := w.tmp.Close(); err != nil || w.failed {
+		os.Remove(tmpName)
+		return
+	}
+
+	w.meta.CreatedAt = time.Now().UTC()
+	meta, err := json.Marshal(w.meta)
+	if err != nil {
+		os.Remove(tmpName)
+		return
+	}
+	var replaced int64
+	if old, err := w.cache.readMeta(w.key); err == nil {
+		replaced = old.Size
+	}
+	if err := os.Rename(tmpName, w.cache.path(w.key, cacheBodySuffix)); err != nil {
+		os.Remove(tmpName)
+		return
+	}
+	if err := os.WriteFile(w.cache.path(w.key, cacheMetaSuffix), meta, 0644); err != nil {
+		os.Remove(w.cache.path(w.key, cacheBodySuffix))
+		return
+	}
+
+	w.cache.added(w.meta.Size, replaced)
+}
+
+// added records a new entry of size bytes that replaced an entry of
+// replaced bytes, and evicts entries once MaxBytes is exceeded.
+func (d *DiskCache) added(size, replaced int64) {
+	if d.MaxBytes <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+	if !d.sized {
+		d.mu.Unlock()
+		entries, err := d.Entries()
+		if err != nil {
+			return
+		}
+		d.mu.Lock()
+		d.size = 0
+		for _, entry := range entries {
+			d.size += entry.Size
+		}
+		d.sized = true
+	} else {
+		d.size += size - replaced
+	}
+	over := d.size > d.MaxBytes
+	d.mu.Unlock()
+
+	if over {
+		_, _, _ = d.Prune()
+	}
+}
+
+// Entries lists the cached responses, oldest first.
+func (d *DiskCache) Entries() ([]CacheEntry, error) {
+	files, err := os.ReadDir(d.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	now := time.Now()
+	var entries []CacheEntry
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, cacheMetaSuffix) {
+			continue
+		}
+		key := strings.TrimSuffix(name, cacheMetaSuffix)
+		if !isCacheKey(key) {
+			continue
+		}
+		meta, err := d.readMeta(key)
+		if err != nil || meta.CreatedAt.IsZero() {
+			continue
+		}
+		entries = append(entries, CacheEntry{
+			Key:         key,
+			RequestType: meta.RequestType,
+			URL:         meta.URL,
+			Size:        meta.Size,
+			CreatedAt:   meta.CreatedAt,
+			Expired:     d.expired(meta.CreatedAt, now),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+	return entries, nil
+}
+
+// Prune removes expired entries and then the oldest entries until the cache
+// fits within MaxBytes. It reports how many entries and bytes were removed.
+func (d *DiskCache) Prune() (int, int64, error) {
+	entries, err := d.Entries()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	removed, freed := 0, int64(0)
+	for _, entry := range entries {
+		if !entry.Expired && (d.MaxBytes <= 0 || total <= d.MaxBytes) {
+			continue
+		}
+		if err := d.remove(entry.Key); err != nil {
+			return removed, freed, err
+		}
+		removed++
+		freed += entry.Size
+		total -= entry.Size
+	}
+
+	d.mu.Lock()
+	d.size = total
+	d.sized = true
+	d.mu.Unlock()
+
+	return removed, freed, nil
+}
+
+// Clear removes every cached entry.
+func (d *DiskCache) Clear() error {
+	entries, err := d.Entries()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := d.remove(entry.Key); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	d.size = 0
+	d.sized = true
+	d.mu.Unlock()
+
+	return nil
+}
+
+func (d *DiskCache) remove(key string) error {
+	for _, suffix := range []string{cacheMetaSuffix, cacheBodySuffix} {
+		if err := os.Remove(d.path(key, suffix)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+	}
+	return nil
+}
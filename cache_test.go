@@ -0,0 +1,230 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheServesRepeatedRequests(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = w.Write([]byte("image-bytes"))
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret", WithCache(t.TempDir(), time.Hour))
+	c.APIURL = server.URL
+
+	for i := 0; i < 2; i++ {
+		data, err := c.FetchImage("https://example.com", RequestOptions{"vw": 800})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(data) != "image-bytes" {
+			t.Fatalf("unexpected body: %q", data)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := c.FetchImageTo(&buf, "https://example.com", RequestOptions{"vw": 800}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "image-bytes" {
+		t.Fatalf("unexpected streamed body: %q", buf.String())
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", calls)
+	}
+
+	if _, err := c.FetchImage("https://example.com", RequestOptions{"vw": 1024}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected different options to miss the cache, got %d calls", calls)
+	}
+}
+
+func TestCacheFreshRequestRefreshesEntry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		_, _ = fmt.Fprintf(w, "render-%d", n)
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret", WithCache(t.TempDir(), time.Hour))
+	c.APIURL = server.URL
+
+	fetch := func(options RequestOptions) string {
+		data, err := c.FetchImage("https://example.com", options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return string(data)
+	}
+
+	if got := fetch(RequestOptions{"vw": 800}); got != "render-1" {
+		t.Fatalf("unexpected first body: %q", got)
+	}
+	if got := fetch(RequestOptions{"vw": 800, "fresh": true}); got != "render-2" {
+		t.Fatalf("expected fresh request to bypass the cache, got %q", got)
+	}
+	if got := fetch(RequestOptions{"vw": 800}); got != "render-2" {
+		t.Fatalf("expected fresh request to refresh the entry, got %q", got)
+	}
+
+	entries, err := c.Cache.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].URL != "https://example.com" || entries[0].RequestType != RequestTypeImage {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+}
+
+func TestCacheTracksReplacedEntrySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 10))
+	}))
+	defer server.Close()
+
+	cache := NewDiskCache(t.TempDir(), time.Hour)
+	cache.MaxBytes = 100
+	c := New("test_key", "test_secret")
+	c.APIURL = server.URL
+	c.Cache = cache
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.FetchImage("https://example.com", RequestOptions{"fresh": true}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	cache.mu.Lock()
+	size := cache.size
+	cache.mu.Unlock()
+	if size != 10 {
+		t.Fatalf("expected tracked size of one 10 byte entry, got %d", size)
+	}
+}
+
+func TestCacheIgnoresForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"package.json", "tsconfig.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(`{"name":"app"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache := NewDiskCache(dir, time.Hour)
+	entries, err := cache.Entries()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Entries() = %#v, %v; want no entries", entries, err)
+	}
+	if removed, _, err := cache.Prune(); err != nil || removed != 0 {
+		t.Fatalf("Prune() removed %d, %v", removed, err)
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("Clear() error: %v", err)
+	}
+	for _, name := range []string{"package.json", "tsconfig.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("expected %s to survive: %v", name, err)
+		}
+	}
+}
+
+func TestCacheContentJSON(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = w.Write([]byte(`{"success":true,"markdown":"# Cached"}`))
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret", WithCache(t.TempDir(), time.Hour))
+	c.APIURL = server.URL
+
+	for i := 0; i < 2; i++ {
+		content, err := c.FetchContent("https://example.com", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if content.Markdown != "# Cached" {
+			t.Fatalf("unexpected content: %#v", content)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", calls)
+	}
+}
+
+func TestCacheDoesNotStoreErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret", WithCache(t.TempDir(), time.Hour))
+	c.APIURL = server.URL
+
+	if _, err := c.FetchImage("https://example.com", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	entries, err := c.Cache.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no cache entries, got %#v", entries)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 10))
+	}))
+	defer server.Close()
+
+	cache := NewDiskCache(t.TempDir(), time.Hour)
+	cache.MaxBytes = 25
+	c := New("test_key", "test_secret")
+	c.APIURL = server.URL
+	c.Cache = cache
+
+	for _, target := range []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"} {
+		if _, err := c.FetchImage(target, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].URL != "https://b.example.com" {
+		t.Fatalf("expected oldest entry to be evicted, got %#v", entries)
+	}
+
+	cache.TTL = time.Nanosecond
+	removed, freed, err := cache.Prune()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 2 || freed != 20 {
+		t.Fatalf("Prune() = %d, %d; want 2, 20", removed, freed)
+	}
+
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -2,10 +2,12 @@ package capture
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +15,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type RequestType string
@@ -38,9 +41,22 @@ type SessionActionPayload map[string]interface{}
 type SessionActionResponse map[string]interface{}
 type SessionResponse map[string]interface{}
 
+// Sentinel errors classifying API failures. Match them with errors.Is on the
+// errors returned by the client.
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session expired")
+	ErrSessionClosed   = errors.New("session closed")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrRateLimited     = errors.New("rate limited")
+	ErrInvalidAction   = errors.New("invalid action")
+)
+
 type SessionsAPIError struct {
 	StatusCode int
 	Body       map[string]interface{}
+	// ActionType is set when the failing request executed a session action.
+	ActionType string
 }
 
 func (e *SessionsAPIError) Error() string {
@@ -50,6 +66,131 @@ func (e *SessionsAPIError) Error() string {
 	return fmt.Sprintf("Capture Sessions API request failed with status %d", e.StatusCode)
 }
 
+// Is reports whether the error matches one of the sentinel errors, so
+// callers can write errors.Is(err, capture.ErrSessionExpired).
+func (e *SessionsAPIError) Is(target error) bool {
+	return target != nil && target == e.kind()
+}
+
+// kind classifies the error by status and the structured code. The message
+// is only consulted for responses without a code.
+func (e *SessionsAPIError) kind() error {
+	code, _ := e.Body["code"].(string)
+	message, _ := e.Body["error"].(string)
+	message = strings.ToLower(message)
+
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case code == "session_closed":
+		return ErrSessionClosed
+	case code == "session_expired" || e.StatusCode == http.StatusGone:
+		return ErrSessionExpired
+	case code == "" && strings.Contains(message, "expired"):
+		return ErrSessionExpired
+	case code == "" && e.StatusCode == http.StatusConflict:
+		return ErrSessionClosed
+	case e.StatusCode == http.StatusNotFound:
+		return ErrSessionNotFound
+	case e.ActionType != "" && (e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity):
+		return ErrInvalidAction
+	}
+	return nil
+}
+
+// CaptureAPIError is returned by the Fetch* methods when the render endpoints
+// answer with a non-200 status. Use errors.As to inspect it.
+type CaptureAPIError struct {
+	StatusCode  int
+	RequestType RequestType
+	// Message is the error reported by the API, if the body contained one.
+	Message string
+	// RetryAfter is the delay requested by the Retry-After header, or zero.
+	RetryAfter time.Duration
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *CaptureAPIError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("Capture API %s request failed with status %d", e.RequestType, e.StatusCode)
+}
+
+// Is matches ErrUnauthorized and ErrRateLimited.
+func (e *CaptureAPIError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return target == ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	}
+	return false
+}
+
+// maxErrorBodySize bounds how much of an error response is kept in memory.
+const maxErrorBodySize = 64 << 10
+
+func newCaptureAPIError(requestType RequestType, resp *http.Response) *CaptureAPIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+	return &CaptureAPIError{
+		StatusCode:  resp.StatusCode,
+		RequestType: requestType,
+		Message:     errorMessage(body),
+		RetryAfter:  parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Header:      resp.Header,
+		Body:        body,
+	}
+}
+
+// errorMessage extracts a human readable message from an error body, which
+// is either a JSON object with an "error" or "message" field or plain text.
+func errorMessage(body []byte) string {
+	trimmed := strings.TrimSpace(string(body))
+	if trimmed == "" {
+		return ""
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		for _, key := range []string{"error", "message"} {
+			if message, ok := decoded[key].(string); ok && message != "" {
+				return message
+			}
+		}
+		return ""
+	}
+
+	if strings.HasPrefix(trimmed, "<") {
+		return ""
+	}
+	return trimmed
+}
+
+// parseRetryAfter interprets a Retry-After header given either as a number
+// of seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
 type Capture struct {
 	APIURL      string
 	EdgeURL     string
@@ -58,6 +199,16 @@ type Capture struct {
 	Secret      string
 	UseEdge     bool
 	Client      *http.Client
+	// RetryPolicy, if set, retries transient failures. See WithRetryPolicy.
+	RetryPolicy *RetryPolicy
+	// Cache, if set, serves repeated render requests from disk. See
+	// WithCache.
+	Cache *DiskCache
+	// Recorder, if set, records every Sessions API request. See
+	// WithRecorder.
+	Recorder *Recorder
+	// Middleware wraps every request sent to the API. See WithMiddleware.
+	Middleware []Middleware
 }
 
 func New(key, secret string, options ...Option) *Capture {
@@ -179,6 +330,11 @@ func (c *Capture) buildURL(requestType RequestType, targetURL string, options Re
 	return finalURL, nil
 }
 
+// BuildURL returns the signed URL for any request type.
+func (c *Capture) BuildURL(requestType RequestType, targetURL string, options RequestOptions) (string, error) {
+	return c.buildURL(requestType, targetURL, options)
+}
+
 func (c *Capture) BuildImageURL(targetURL string, options RequestOptions) (string, error) {
 	return c.buildURL(RequestTypeImage, targetURL, options)
 }
@@ -199,47 +355,80 @@ func (c *Capture) BuildAnimatedURL(targetURL string, options RequestOptions) (st
 	return c.buildURL(RequestTypeAnimated, targetURL, options)
 }
 
-func (c *Capture) FetchImage(targetURL string, options RequestOptions) ([]byte, error) {
-	url, err := c.BuildImageURL(targetURL, options)
-	if err != nil {
-		return nil, err
+func (t RequestType) label() string {
+	if t == RequestTypePDF {
+		return "PDF"
 	}
+	return string(t)
+}
 
-	resp, err := c.Client.Get(url)
+// open builds the signed URL for requestType and returns the body of a
+// successful response, served from c.Cache when a fresh entry exists. Any
+// non-200 status is reported as a *CaptureAPIError. Callers must close the
+// returned body.
+func (c *Capture) open(ctx context.Context, requestType RequestType, targetURL string, options RequestOptions) (io.ReadCloser, error) {
+	url, err := c.buildURL(requestType, targetURL, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch image: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	cacheURL := url
+	if c.Cache != nil {
+		if options["fresh"] == true {
+			// Refresh the entry that the same request without "fresh" reads.
+			cached := make(RequestOptions, len(options))
+			for k, v := range options {
+				cached[k] = v
+			}
+			delete(cached, "fresh")
+			if cacheURL, err = c.buildURL(requestType, targetURL, cached); err != nil {
+				return nil, err
+			}
+		} else if body, ok := c.Cache.open(cacheURL); ok {
+			return body, nil
+		}
 	}
 
-	buf, err := io.ReadAll(resp.Body)
+	resp, err := c.get(ctx, requestType, targetURL, options, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	return buf, nil
+	if c.Cache != nil {
+		return c.Cache.tee(cacheURL, requestType, targetURL, resp.Body), nil
+	}
+	return resp.Body, nil
 }
 
-func (c *Capture) FetchPDF(targetURL string, options RequestOptions) ([]byte, error) {
-	url, err := c.BuildPDFURL(targetURL, options)
+// get issues the GET request for a signed URL, returning the response only
+// when the API answered with 200 OK.
+func (c *Capture) get(ctx context.Context, requestType RequestType, targetURL string, options RequestOptions, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to build %s request: %w", requestType.label(), err)
 	}
 
-	resp, err := c.Client.Get(url)
+	resp, err := c.send(&APIRequest{RequestType: requestType, TargetURL: targetURL, Options: options, HTTP: req}, true)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch PDF: %w", err)
+		return nil, fmt.Errorf("failed to fetch %s: %w", requestType.label(), err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+		defer resp.Body.Close()
+		return nil, newCaptureAPIError(requestType, resp)
 	}
 
-	buf, err := io.ReadAll(resp.Body)
+	return resp, nil
+}
+
+func (c *Capture) fetchBytes(ctx context.Context, requestType RequestType, targetURL string, options RequestOptions) ([]byte, error) {
+	body, err := c.open(ctx, requestType, targetURL, options)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	buf, err := io.ReadAll(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -247,6 +436,39 @@ func (c *Capture) FetchPDF(targetURL string, options RequestOptions) ([]byte, er
 	return buf, nil
 }
 
+func (c *Capture) fetchJSON(ctx context.Context, requestType RequestType, targetURL string, options RequestOptions, out interface{}) error {
+	buf, err := c.fetchBytes(ctx, requestType, targetURL, options)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(buf, out); err != nil {
+		return fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Capture) FetchImage(targetURL string, options RequestOptions) ([]byte, error) {
+	return c.FetchImageContext(context.Background(), targetURL, options)
+}
+
+// FetchImageContext is like FetchImage but aborts the request when ctx is
+// canceled or its deadline expires.
+func (c *Capture) FetchImageContext(ctx context.Context, targetURL string, options RequestOptions) ([]byte, error) {
+	return c.fetchBytes(ctx, RequestTypeImage, targetURL, options)
+}
+
+func (c *Capture) FetchPDF(targetURL string, options RequestOptions) ([]byte, error) {
+	return c.FetchPDFContext(context.Background(), targetURL, options)
+}
+
+// FetchPDFContext is like FetchPDF but aborts the request when ctx is
+// canceled or its deadline expires.
+func (c *Capture) FetchPDFContext(ctx context.Context, targetURL string, options RequestOptions) ([]byte, error) {
+	return c.fetchBytes(ctx, RequestTypePDF, targetURL, options)
+}
+
 type ContentResponse struct {
 	Success     bool   `json:"success"`
 	HTML        string `json:"html"`
@@ -255,26 +477,16 @@ type ContentResponse struct {
 }
 
 func (c *Capture) FetchContent(targetURL string, options RequestOptions) (*ContentResponse, error) {
-	url, err := c.BuildContentURL(targetURL, options)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.Client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch content: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
-	}
+	return c.FetchContentContext(context.Background(), targetURL, options)
+}
 
+// FetchContentContext is like FetchContent but aborts the request when ctx is
+// canceled or its deadline expires.
+func (c *Capture) FetchContentContext(ctx context.Context, targetURL string, options RequestOptions) (*ContentResponse, error) {
 	var contentResp ContentResponse
-	if err := json.NewDecoder(resp.Body).Decode(&contentResp); err != nil {
-		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	if err := c.fetchJSON(ctx, RequestTypeContent, targetURL, options, &contentResp); err != nil {
+		return nil, err
 	}
-
 	return &contentResp, nil
 }
 
@@ -284,51 +496,27 @@ type MetadataResponse struct {
 }
 
 func (c *Capture) FetchMetadata(targetURL string, options RequestOptions) (*MetadataResponse, error) {
-	url, err := c.BuildMetadataURL(targetURL, options)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.Client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
-	}
+	return c.FetchMetadataContext(context.Background(), targetURL, options)
+}
 
+// FetchMetadataContext is like FetchMetadata but aborts the request when ctx
+// is canceled or its deadline expires.
+func (c *Capture) FetchMetadataContext(ctx context.Context, targetURL string, options RequestOptions) (*MetadataResponse, error) {
 	var metadataResp MetadataResponse
-	if err := json.NewDecoder(resp.Body).Decode(&metadataResp); err != nil {
-		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	if err := c.fetchJSON(ctx, RequestTypeMetadata, targetURL, options, &metadataResp); err != nil {
+		return nil, err
 	}
-
 	return &metadataResp, nil
 }
 
 func (c *Capture) FetchAnimated(targetURL string, options RequestOptions) ([]byte, error) {
-	url, err := c.BuildAnimatedURL(targetURL, options)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.Client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch animated: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
-	}
-
-	buf, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
+	return c.FetchAnimatedContext(context.Background(), targetURL, options)
+}
 
-	return buf, nil
+// FetchAnimatedContext is like FetchAnimated but aborts the request when ctx
+// is canceled or its deadline expires.
+func (c *Capture) FetchAnimatedContext(ctx context.Context, targetURL string, options RequestOptions) ([]byte, error) {
+	return c.fetchBytes(ctx, RequestTypeAnimated, targetURL, options)
 }
 
 func (c *Capture) sessionsBearerToken() (string, error) {
@@ -352,24 +540,38 @@ type SessionRequestPreview struct {
 	Body   interface{} `json:"body,omitempty"`
 }
 
-func (c *Capture) sessionRequest(preview SessionRequestPreview, out interface{}) error {
+func (c *Capture) sessionRequest(ctx context.Context, preview SessionRequestPreview, out interface{}) error {
+	start := time.Now()
+	statusCode, respBody, err := c.sendSessionRequest(ctx, preview)
+	elapsed := time.Since(start)
+	if err == nil {
+		err = decodeSessionResponse(preview, statusCode, respBody, out)
+	}
+	if c.Recorder != nil {
+		c.Recorder.record(newTimelineEntry(preview, start, elapsed, statusCode, respBody, err))
+	}
+	return err
+}
+
+// sendSessionRequest sends preview and returns the response status and body.
+func (c *Capture) sendSessionRequest(ctx context.Context, preview SessionRequestPreview) (int, []byte, error) {
 	token, err := c.sessionsBearerToken()
 	if err != nil {
-		return err
+		return 0, nil, err
 	}
 
 	var requestBody io.Reader
 	if preview.Body != nil {
 		data, err := json.Marshal(preview.Body)
 		if err != nil {
-			return fmt.Errorf("failed to encode session request body: %w", err)
+			return 0, nil, fmt.Errorf("failed to encode session request body: %w", err)
 		}
 		requestBody = bytes.NewReader(data)
 	}
 
-	req, err := http.NewRequest(preview.Method, preview.URL, requestBody)
+	req, err := http.NewRequestWithContext(ctx, preview.Method, preview.URL, requestBody)
 	if err != nil {
-		return fmt.Errorf("failed to build session request: %w", err)
+		return 0, nil, fmt.Errorf("failed to build session request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+token)
@@ -377,25 +579,34 @@ func (c *Capture) sessionRequest(preview SessionRequestPreview, out interface{})
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	resp, err := c.Client.Do(req)
+	resp, err := c.send(&APIRequest{Session: &preview, HTTP: req}, c.RetryPolicy != nil && c.RetryPolicy.RetrySessions)
 	if err != nil {
-		return fmt.Errorf("failed to execute session request: %w", err)
+		return 0, nil, fmt.Errorf("failed to execute session request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read session response body: %w", err)
+		return resp.StatusCode, nil, fmt.Errorf("failed to read session response body: %w", err)
 	}
+	return resp.StatusCode, respBody, nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+// decodeSessionResponse turns a Sessions API response into out, or into a
+// SessionsAPIError for a non-2xx status.
+func decodeSessionResponse(preview SessionRequestPreview, statusCode int, respBody []byte, out interface{}) error {
+	if statusCode < 200 || statusCode >= 300 {
 		decoded := map[string]interface{}{}
 		if len(respBody) > 0 {
 			if err := json.Unmarshal(respBody, &decoded); err != nil {
 				decoded["error"] = string(respBody)
 			}
 		}
-		return &SessionsAPIError{StatusCode: resp.StatusCode, Body: decoded}
+		apiErr := &SessionsAPIError{StatusCode: statusCode, Body: decoded}
+		if body, ok := preview.Body.(map[string]interface{}); ok {
+			apiErr.ActionType, _ = body["type"].(string)
+		}
+		return apiErr
 	}
 
 	if len(respBody) == 0 {
@@ -436,32 +647,56 @@ func (c *Capture) BuildExecuteActionRequest(sessionID, actionType string, payloa
 }
 
 func (c *Capture) CreateSession(options *CreateSessionOptions) (SessionResponse, error) {
+	return c.CreateSessionContext(context.Background(), options)
+}
+
+// CreateSessionContext is like CreateSession but aborts the request when ctx
+// is canceled or its deadline expires.
+func (c *Capture) CreateSessionContext(ctx context.Context, options *CreateSessionOptions) (SessionResponse, error) {
 	var response SessionResponse
-	if err := c.sessionRequest(c.BuildCreateSessionRequest(options), &response); err != nil {
+	if err := c.sessionRequest(ctx, c.BuildCreateSessionRequest(options), &response); err != nil {
 		return nil, err
 	}
 	return response, nil
 }
 
 func (c *Capture) GetSession(sessionID string) (SessionResponse, error) {
+	return c.GetSessionContext(context.Background(), sessionID)
+}
+
+// GetSessionContext is like GetSession but aborts the request when ctx is
+// canceled or its deadline expires.
+func (c *Capture) GetSessionContext(ctx context.Context, sessionID string) (SessionResponse, error) {
 	var response SessionResponse
-	if err := c.sessionRequest(c.BuildGetSessionRequest(sessionID), &response); err != nil {
+	if err := c.sessionRequest(ctx, c.BuildGetSessionRequest(sessionID), &response); err != nil {
 		return nil, err
 	}
 	return response, nil
 }
 
 func (c *Capture) CloseSession(sessionID string) (SessionResponse, error) {
+	return c.CloseSessionContext(context.Background(), sessionID)
+}
+
+// CloseSessionContext is like CloseSession but aborts the request when ctx is
+// canceled or its deadline expires.
+func (c *Capture) CloseSessionContext(ctx context.Context, sessionID string) (SessionResponse, error) {
 	var response SessionResponse
-	if err := c.sessionRequest(c.BuildCloseSessionRequest(sessionID), &response); err != nil {
+	if err := c.sessionRequest(ctx, c.BuildCloseSessionRequest(sessionID), &response); err != nil {
 		return nil, err
 	}
 	return response, nil
 }
 
 func (c *Capture) ExecuteAction(sessionID, actionType string, payload SessionActionPayload) (SessionActionResponse, error) {
+	return c.ExecuteActionContext(context.Background(), sessionID, actionType, payload)
+}
+
+// ExecuteActionContext is like ExecuteAction but aborts the request when ctx
+// is canceled or its deadline expires.
+func (c *Capture) ExecuteActionContext(ctx context.Context, sessionID, actionType string, payload SessionActionPayload) (SessionActionResponse, error) {
 	var response SessionActionResponse
-	if err := c.sessionRequest(c.BuildExecuteActionRequest(sessionID, actionType, payload), &response); err != nil {
+	if err := c.sessionRequest(ctx, c.BuildExecuteActionRequest(sessionID, actionType, payload), &response); err != nil {
 		return nil, err
 	}
 	return response, nil
@@ -1,12 +1,15 @@
 package capture
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -545,6 +548,215 @@ func TestSessionsAPIError(t *testing.T) {
 	}
 }
 
+func TestSessionsAPIErrorSentinels(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    *SessionsAPIError
+		target error
+	}{
+		{"not found", &SessionsAPIError{StatusCode: 404, Body: map[string]interface{}{"error": "Session not found"}}, ErrSessionNotFound},
+		{"expired by status", &SessionsAPIError{StatusCode: 410}, ErrSessionExpired},
+		{"expired by message", &SessionsAPIError{StatusCode: 409, Body: map[string]interface{}{"error": "Session has expired"}}, ErrSessionExpired},
+		{"expired by code", &SessionsAPIError{StatusCode: 404, Body: map[string]interface{}{"code": "session_expired"}}, ErrSessionExpired},
+		{"closed by code", &SessionsAPIError{StatusCode: 410, Body: map[string]interface{}{"code": "session_closed"}}, ErrSessionClosed},
+		{"closed by status", &SessionsAPIError{StatusCode: 409, Body: map[string]interface{}{"error": "Session is closed"}}, ErrSessionClosed},
+		{"closed code over message", &SessionsAPIError{StatusCode: 409, Body: map[string]interface{}{"code": "session_closed", "error": "Session expired and was closed"}}, ErrSessionClosed},
+		{"not found code over message", &SessionsAPIError{StatusCode: 404, Body: map[string]interface{}{"code": "session_not_found", "error": "No session, it may have expired"}}, ErrSessionNotFound},
+		{"action code over message", &SessionsAPIError{StatusCode: 400, ActionType: "setCookies", Body: map[string]interface{}{"code": "invalid_payload", "error": "cookie has already expired"}}, ErrInvalidAction},
+		{"unauthorized", &SessionsAPIError{StatusCode: 401}, ErrUnauthorized},
+		{"forbidden", &SessionsAPIError{StatusCode: 403}, ErrUnauthorized},
+		{"rate limited", &SessionsAPIError{StatusCode: 429}, ErrRateLimited},
+		{"invalid action", &SessionsAPIError{StatusCode: 400, ActionType: "click"}, ErrInvalidAction},
+	}
+
+	sentinels := []error{ErrSessionNotFound, ErrSessionExpired, ErrSessionClosed, ErrUnauthorized, ErrRateLimited, ErrInvalidAction}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped := fmt.Errorf("wrapped: %w", tt.err)
+			for _, sentinel := range sentinels {
+				if got, want := errors.Is(wrapped, sentinel), sentinel == tt.target; got != want {
+					t.Errorf("errors.Is(%v) = %v, want %v", sentinel, got, want)
+				}
+			}
+		})
+	}
+
+	if errors.Is(&SessionsAPIError{StatusCode: 400}, ErrInvalidAction) {
+		t.Error("400 outside of an action should not be ErrInvalidAction")
+	}
+}
+
+func TestExecuteActionInvalidAction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "Unknown action type"})
+	}))
+	defer server.Close()
+
+	c := New("user_123", "secret")
+	c.SessionsURL = server.URL
+
+	_, err := c.ExecuteAction("sess_123", "fly", nil)
+	if !errors.Is(err, ErrInvalidAction) {
+		t.Fatalf("expected ErrInvalidAction, got %v", err)
+	}
+	var apiErr *SessionsAPIError
+	if !errors.As(err, &apiErr) || apiErr.ActionType != "fly" {
+		t.Fatalf("expected ActionType to be recorded, got %#v", apiErr)
+	}
+}
+
+func TestCaptureAPIErrorSentinels(t *testing.T) {
+	if !errors.Is(&CaptureAPIError{StatusCode: 429}, ErrRateLimited) {
+		t.Error("expected 429 to match ErrRateLimited")
+	}
+	if !errors.Is(&CaptureAPIError{StatusCode: 401}, ErrUnauthorized) {
+		t.Error("expected 401 to match ErrUnauthorized")
+	}
+	if errors.Is(&CaptureAPIError{StatusCode: 500}, ErrRateLimited) {
+		t.Error("did not expect 500 to match ErrRateLimited")
+	}
+}
+
+func TestFetchContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := New("test_key", "test_secret")
+	c.APIURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.FetchImageContext(ctx, "https://example.com", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := c.FetchContentContext(ctx, "https://example.com", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSessionRequestContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c := New("user_123", "secret")
+	c.SessionsURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.CreateSessionContext(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := c.ExecuteActionContext(ctx, "sess_123", "goto", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestFetchImageContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/test_key/") || !strings.HasSuffix(r.URL.Path, "/image") {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte("png-bytes"))
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret")
+	c.APIURL = server.URL
+
+	data, err := c.FetchImageContext(context.Background(), "https://example.com", RequestOptions{"vw": 1280})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "png-bytes" {
+		t.Fatalf("unexpected body: %q", data)
+	}
+}
+
+func TestCaptureAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.Header().Set("X-Request-Id", "req_123")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": "Rate limit exceeded",
+		})
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret")
+	c.APIURL = server.URL
+
+	_, err := c.FetchPDF("https://example.com", nil)
+	var apiErr *CaptureAPIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected CaptureAPIError, got %T (%v)", err, err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RequestType != RequestTypePDF {
+		t.Fatalf("unexpected error: %#v", apiErr)
+	}
+	if apiErr.Message != "Rate limit exceeded" || apiErr.Error() != "Rate limit exceeded" {
+		t.Fatalf("unexpected message: %q", apiErr.Message)
+	}
+	if apiErr.RetryAfter != 7*time.Second {
+		t.Fatalf("RetryAfter = %v, want 7s", apiErr.RetryAfter)
+	}
+	if apiErr.Header.Get("X-Request-Id") != "req_123" {
+		t.Fatalf("expected response headers to be kept, got %#v", apiErr.Header)
+	}
+}
+
+func TestCaptureAPIErrorWithoutBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret")
+	c.APIURL = server.URL
+
+	_, err := c.FetchMetadata("https://example.com", nil)
+	var apiErr *CaptureAPIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected CaptureAPIError, got %T (%v)", err, err)
+	}
+	if apiErr.Error() != "Capture API metadata request failed with status 502" {
+		t.Fatalf("unexpected message: %q", apiErr.Error())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 6, 7, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"Sun, 07 Jun 2026 00:00:30 GMT", 30 * time.Second},
+		{"Sat, 06 Jun 2026 23:59:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
 func TestScreenshotOptions(t *testing.T) {
 	c := New("test_key", "test_secret")
 
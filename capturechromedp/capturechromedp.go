@@ -0,0 +1,87 @@
+// Package capturechromedp connects chromedp to Capture CDP browser sessions.
+//
+//	allocCtx, cancel, err := capturechromedp.NewRemoteAllocator(ctx, client, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer cancel() // also closes the session
+//
+//	taskCtx, cancelTask := chromedp.NewContext(allocCtx)
+//	defer cancelTask()
+//	err = chromedp.Run(taskCtx, chromedp.Navigate("https://example.com"))
+package capturechromedp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	capture "github.com/techulus/capture-go"
+)
+
+// CloseTimeout bounds the CloseSession request made when an allocator is
+// canceled.
+var CloseTimeout = 30 * time.Second
+
+type sessionKey struct{}
+
+// NewRemoteAllocator creates a CDP session and returns a chromedp allocator
+// context connected to its connectUrl. CDP is enabled regardless of options.
+//
+// The returned cancel function stops the allocator and closes the session.
+// The session is also closed when ctx is done, but cancel should still be
+// called to release resources.
+func NewRemoteAllocator(ctx context.Context, client *capture.Capture, options *capture.CreateSessionOptions, opts ...chromedp.RemoteAllocatorOption) (context.Context, context.CancelFunc, error) {
+	sessionOptions := capture.CreateSessionOptions{}
+	if options != nil {
+		sessionOptions = *options
+	}
+	sessionOptions.CDP = true
+
+	session, err := client.CreateSessionTypedContext(ctx, &sessionOptions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CDP session: %w", err)
+	}
+	if session.ConnectURL == "" {
+		closeSession(session)
+		return nil, nil, fmt.Errorf("session %s did not return a connectUrl", session.ID)
+	}
+
+	// The connectUrl is a complete WebSocket endpoint, not a DevTools host
+	// to resolve through /json/version.
+	opts = append([]chromedp.RemoteAllocatorOption{chromedp.NoModifyURL}, opts...)
+	allocCtx, cancelAlloc := chromedp.NewRemoteAllocator(context.WithValue(ctx, sessionKey{}, session), session.ConnectURL, opts...)
+
+	// The handle in allocCtx belongs to the caller, who may close it at any
+	// time. Closing on ctx done runs on its own goroutine, so it uses a
+	// separate handle instead of touching the caller's.
+	var once sync.Once
+	stop := context.AfterFunc(allocCtx, func() {
+		once.Do(func() { closeSession(client.AttachSession(session.ID)) })
+	})
+
+	return allocCtx, func() {
+		cancelAlloc()
+		stop()
+		once.Do(func() { closeSession(session) })
+	}, nil
+}
+
+// SessionFromContext returns the Capture session behind an allocator
+// context created by NewRemoteAllocator, or any context derived from it.
+// The session is bound to the client, so its actions and Close work.
+func SessionFromContext(ctx context.Context) *capture.Session {
+	session, _ := ctx.Value(sessionKey{}).(*capture.Session)
+	return session
+}
+
+// closeSession closes session within CloseTimeout. Errors are ignored, since
+// the caller may already have closed the session through the handle returned
+// by SessionFromContext.
+func closeSession(session *capture.Session) {
+	ctx, cancel := context.WithTimeout(context.Background(), CloseTimeout)
+	defer cancel()
+	_ = session.CloseContext(ctx)
+}
@@ -0,0 +1,138 @@
+package capturechromedp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func newSessionsServer(t *testing.T, connectURL string, closes *int32) *capture.Capture {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if strings.HasSuffix(r.URL.Path, "/actions") {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "url": "https://example.com"})
+				return
+			}
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["cdp"] != true {
+				t.Errorf("expected a CDP session, got %v", body)
+			}
+		case http.MethodDelete:
+			atomic.AddInt32(closes, 1)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"session": map[string]interface{}{"id": "sess_1", "status": "active", "connectUrl": connectURL},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	c := capture.New("key", "secret")
+	c.SessionsURL = server.URL
+	return c
+}
+
+func TestNewRemoteAllocator(t *testing.T) {
+	var closes int32
+	client := newSessionsServer(t, "wss://cdp.example.com/sess_1?token=abc", &closes)
+
+	allocCtx, cancel, err := NewRemoteAllocator(context.Background(), client, &capture.CreateSessionOptions{MaxTtlSeconds: 60})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	session := SessionFromContext(allocCtx)
+	if session == nil || session.ID != "sess_1" || session.ConnectURL != "wss://cdp.example.com/sess_1?token=abc" {
+		t.Fatalf("unexpected session: %#v", session)
+	}
+
+	cancel()
+	cancel()
+	if closes != 1 {
+		t.Fatalf("expected session to be closed once, got %d", closes)
+	}
+}
+
+func TestNewRemoteAllocatorClosesOnParentCancel(t *testing.T) {
+	var closes int32
+	client := newSessionsServer(t, "wss://cdp.example.com/sess_1", &closes)
+
+	ctx, cancelParent := context.WithCancel(context.Background())
+	_, cancel, err := NewRemoteAllocator(ctx, client, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cancelParent()
+	cancel()
+	if closes != 1 {
+		t.Fatalf("expected session to be closed once, got %d", closes)
+	}
+}
+
+func TestSessionCloseRacesParentCancel(t *testing.T) {
+	var closes int32
+	client := newSessionsServer(t, "wss://cdp.example.com/sess_1", &closes)
+
+	ctx, cancelParent := context.WithCancel(context.Background())
+	allocCtx, cancel, err := NewRemoteAllocator(ctx, client, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cancel()
+
+	session := SessionFromContext(allocCtx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = session.Close()
+	}()
+	cancelParent()
+	<-done
+	cancel()
+	if atomic.LoadInt32(&closes) == 0 {
+		t.Fatal("expected session to be closed")
+	}
+}
+
+func TestNewRemoteAllocatorWithoutConnectURL(t *testing.T) {
+	var closes int32
+	client := newSessionsServer(t, "", &closes)
+
+	if _, _, err := NewRemoteAllocator(context.Background(), client, nil); err == nil {
+		t.Fatal("expected error when the session has no connectUrl")
+	}
+	if closes != 1 {
+		t.Fatalf("expected the unusable session to be closed, got %d closes", closes)
+	}
+}
+
+func TestSessionFromContextIsBound(t *testing.T) {
+	var closes int32
+	client := newSessionsServer(t, "wss://cdp.example.com/sess_1", &closes)
+
+	allocCtx, cancel, err := NewRemoteAllocator(context.Background(), client, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cancel()
+
+	session := SessionFromContext(allocCtx)
+	result, err := session.Goto(context.Background(), capture.GotoAction{URL: "https://example.com"})
+	if err != nil || result.URL != "https://example.com" {
+		t.Fatalf("Goto() = %+v, %v", result, err)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	cancel()
+	if closes != 1 {
+		t.Fatalf("expected session to be closed once, got %d", closes)
+	}
+}
@@ -0,0 +1,385 @@
+// Package capturetest provides an in-process fake of the Capture render and
+// Sessions APIs for testing code that uses capture.Capture.
+//
+//	server := capturetest.NewServer()
+//	defer server.Close()
+//
+//	server.SetContent("https://example.com", capture.ContentResponse{Success: true, Markdown: "# Hello"})
+//	server.AddFault(capturetest.Fault{StatusCode: http.StatusTooManyRequests, Times: 1})
+//
+//	client := server.Client() // signed with server.Key and server.Secret
+//	content, err := client.FetchContent("https://example.com", nil)
+//
+//	for _, req := range server.Requests() {
+//		// assert on req.RequestType, req.TargetURL, req.ActionType, ...
+//	}
+//
+// Render requests are rejected unless their md5 token matches the query
+// signed with the server's secret, and session requests unless they carry
+// the matching bearer token, so signing bugs surface in tests.
+package capturetest
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	capture "github.com/techulus/capture-go"
+)
+
+// Default credentials of a server created without WithCredentials.
+const (
+	DefaultKey    = "test_key"
+	DefaultSecret = "test_secret"
+)
+
+// Fixture is a canned response for render requests.
+type Fixture struct {
+	// StatusCode defaults to 200.
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Fault makes the server misbehave for matching requests. Latency is applied
+// first; a non-zero StatusCode then replaces the normal response.
+type Fault struct {
+	// Match limits the fault to the requests it returns true for. A nil
+	// Match applies to every request.
+	Match      func(Request) bool
+	Latency    time.Duration
+	StatusCode int
+	// RetryAfter sets the Retry-After header of the injected response.
+	RetryAfter time.Duration
+	// Times is how many requests the fault applies to before it is removed.
+	// Zero applies it to every matching request.
+	Times int
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Time   time.Time
+
+	// RequestType, TargetURL and Options are set for render requests.
+	RequestType capture.RequestType
+	TargetURL   string
+	Options     url.Values
+
+	// SessionID, ActionType and Body are set for Sessions API requests.
+	SessionID  string
+	ActionType string
+	Body       map[string]interface{}
+
+	// StatusCode is the status the server answered with, or zero when the
+	// client went away first.
+	StatusCode int
+}
+
+// IsSession reports whether r was sent to the Sessions API.
+func (r Request) IsSession() bool {
+	return strings.HasPrefix(r.Path, "/v1/sessions")
+}
+
+// Option configures an API.
+type Option func(*API)
+
+// WithCredentials sets the key and secret requests must be signed with.
+func WithCredentials(key, secret string) Option {
+	return func(a *API) {
+		a.Key = key
+		a.Secret = secret
+	}
+}
+
+// API is the fake Capture API as an http.Handler. Use NewServer to run it in
+// an httptest server, or serve it yourself. It is safe for concurrent use.
+type API struct {
+	Key    string
+	Secret string
+
+	mu       sync.Mutex
+	fixtures map[fixtureKey]Fixture
+	faults   []*Fault
+	requests []Request
+	sessions map[string]*fakeSession
+	actions  map[string]ActionFunc
+	nextID   int
+}
+
+type fixtureKey struct {
+	requestType capture.RequestType
+	targetURL   string
+}
+
+// NewAPI returns a fake API with the default credentials and outputs.
+func NewAPI(options ...Option) *API {
+	a := &API{
+		Key:      DefaultKey,
+		Secret:   DefaultSecret,
+		fixtures: make(map[fixtureKey]Fixture),
+		sessions: make(map[string]*fakeSession),
+		actions:  make(map[string]ActionFunc),
+	}
+	for _, option := range options {
+		option(a)
+	}
+	return a
+}
+
+// Server is an API served by an httptest.Server.
+type Server struct {
+	*API
+	*httptest.Server
+}
+
+// NewServer starts a fake API server. Call Close when done.
+func NewServer(options ...Option) *Server {
+	api := NewAPI(options...)
+	return &Server{API: api, Server: httptest.NewServer(api)}
+}
+
+// Client returns a capture client that sends render and session requests to
+// the server, signed with its credentials.
+func (s *Server) Client(options ...capture.Option) *capture.Capture {
+	options = append([]capture.Option{capture.WithHTTPClient(s.Server.Client())}, options...)
+	c := capture.New(s.Key, s.Secret, options...)
+	c.APIURL = s.URL
+	c.EdgeURL = s.URL
+	c.SessionsURL = s.URL
+	return c
+}
+
+// SetFixture serves fixture for requestType renders of targetURL. An empty
+// targetURL sets the fallback for every URL without its own fixture.
+func (a *API) SetFixture(requestType capture.RequestType, targetURL string, fixture Fixture) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fixtures[fixtureKey{requestType, targetURL}] = fixture
+}
+
+// SetContent serves content for content requests of targetURL.
+func (a *API) SetContent(targetURL string, content capture.ContentResponse) {
+	a.setJSONFixture(capture.RequestTypeContent, targetURL, content)
+}
+
+// SetMetadata serves metadata for metadata requests of targetURL.
+func (a *API) SetMetadata(targetURL string, metadata map[string]interface{}) {
+	a.setJSONFixture(capture.RequestTypeMetadata, targetURL, capture.MetadataResponse{Success: true, Metadata: metadata})
+}
+
+func (a *API) setJSONFixture(requestType capture.RequestType, targetURL string, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		panic("capturetest: " + err.Error())
+	}
+	a.SetFixture(requestType, targetURL, Fixture{ContentType: "application/json", Body: body})
+}
+
+// AddFault injects fault into the responses of matching requests.
+func (a *API) AddFault(fault Fault) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.faults = append(a.faults, &fault)
+}
+
+// ClearFaults removes every fault.
+func (a *API) ClearFaults() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.faults = nil
+}
+
+// Requests returns the requests received so far, in order.
+func (a *API) Requests() []Request {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Request(nil), a.requests...)
+}
+
+// ResetRequests forgets the received requests.
+func (a *API) ResetRequests() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests = nil
+}
+
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Time:   time.Now(),
+	}
+
+	if req.IsSession() {
+		a.parseSessionRequest(r, &req)
+	} else {
+		a.parseRenderRequest(r, &req)
+	}
+
+	// The request is recorded before the response is sent, so it is visible
+	// to a client that calls Requests as soon as its call returns.
+	rec := &statusRecorder{ResponseWriter: w, onStatus: func(status int) {
+		req.StatusCode = status
+		a.mu.Lock()
+		a.requests = append(a.requests, req)
+		a.mu.Unlock()
+	}}
+	defer rec.finish()
+
+	if fault := a.takeFault(req); fault != nil {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.StatusCode != 0 {
+			if fault.RetryAfter > 0 {
+				rec.Header().Set("Retry-After", strconv.Itoa(int((fault.RetryAfter+time.Second-1)/time.Second)))
+			}
+			writeError(rec, fault.StatusCode, http.StatusText(fault.StatusCode))
+			return
+		}
+	}
+
+	if req.IsSession() {
+		a.serveSession(rec, r, req)
+		return
+	}
+	a.serveRender(rec, r, req)
+}
+
+// takeFault returns the first fault matching req, consuming one of its uses.
+func (a *API) takeFault(req Request) *Fault {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, fault := range a.faults {
+		if fault.Match != nil && !fault.Match(req) {
+			continue
+		}
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				a.faults = append(a.faults[:i:i], a.faults[i+1:]...)
+			}
+		}
+		return fault
+	}
+	return nil
+}
+
+func (a *API) parseRenderRequest(r *http.Request, req *Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 3 {
+		req.RequestType = capture.RequestType(parts[2])
+	}
+	req.Options = r.URL.Query()
+	req.TargetURL = req.Options.Get("url")
+}
+
+func (a *API) serveRender(w http.ResponseWriter, r *http.Request, req Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || r.Method != http.MethodGet {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if parts[0] != a.Key {
+		writeError(w, http.StatusUnauthorized, "invalid key")
+		return
+	}
+	if parts[1] != signQuery(a.Secret, r.URL.RawQuery) {
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+	if req.TargetURL == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+
+	a.mu.Lock()
+	fixture, ok := a.fixtures[fixtureKey{req.RequestType, req.TargetURL}]
+	if !ok {
+		fixture, ok = a.fixtures[fixtureKey{req.RequestType, ""}]
+	}
+	a.mu.Unlock()
+	if !ok {
+		var err error
+		fixture, err = defaultFixture(req.RequestType, req.TargetURL, req.Options)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+	}
+
+	if fixture.ContentType != "" {
+		w.Header().Set("Content-Type", fixture.ContentType)
+	}
+	if fixture.StatusCode != 0 {
+		w.WriteHeader(fixture.StatusCode)
+	}
+	_, _ = w.Write(fixture.Body)
+}
+
+// signQuery computes the token the client derives from the query string.
+func signQuery(secret, query string) string {
+	hash := md5.Sum([]byte(secret + query))
+	return hex.EncodeToString(hash[:])
+}
+
+func (a *API) bearerToken() string {
+	return base64.StdEncoding.EncodeToString([]byte(a.Key + ":" + a.Secret))
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{"success": false, "error": message})
+}
+
+// statusRecorder reports the response status once, before anything is
+// written to the client.
+type statusRecorder struct {
+	http.ResponseWriter
+	onStatus func(int)
+	reported bool
+}
+
+func (r *statusRecorder) report(status int) {
+	if !r.reported {
+		r.reported = true
+		r.onStatus(status)
+	}
+}
+
+// finish reports a request that ended without a response, e.g. because the
+// client went away during injected latency.
+func (r *statusRecorder) finish() {
+	r.report(0)
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.report(status)
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	r.report(http.StatusOK)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,135 @@
+package capturetest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func TestServerServesFixtures(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.SetFixture(capture.RequestTypeImage, "https://example.com", Fixture{ContentType: "image/png", Body: []byte("png-bytes")})
+	server.SetContent("https://example.com", capture.ContentResponse{Success: true, Markdown: "# Example"})
+	server.SetMetadata("", map[string]interface{}{"title": "Any page"})
+
+	client := server.Client()
+	image, err := client.FetchImage("https://example.com", capture.RequestOptions{"vw": 800, "full": true})
+	if err != nil || string(image) != "png-bytes" {
+		t.Fatalf("FetchImage() = %q, %v", image, err)
+	}
+	content, err := client.FetchContent("https://example.com", nil)
+	if err != nil || content.Markdown != "# Example" {
+		t.Fatalf("FetchContent() = %+v, %v", content, err)
+	}
+	metadata, err := client.FetchMetadata("https://other.example.com", nil)
+	if err != nil || metadata.Metadata["title"] != "Any page" {
+		t.Fatalf("FetchMetadata() = %+v, %v", metadata, err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	first := requests[0]
+	if first.RequestType != capture.RequestTypeImage || first.TargetURL != "https://example.com" || first.Options.Get("vw") != "800" || first.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected recorded request: %+v", first)
+	}
+	if first.IsSession() {
+		t.Fatal("render request reported as a session request")
+	}
+
+	server.ResetRequests()
+	if len(server.Requests()) != 0 {
+		t.Fatal("expected ResetRequests to clear requests")
+	}
+}
+
+func TestServerRejectsBadSignatures(t *testing.T) {
+	server := NewServer(WithCredentials("key_1", "secret_1"))
+	defer server.Close()
+
+	wrongSecret := server.Client()
+	wrongSecret.Secret = "other"
+	_, err := wrongSecret.FetchPDF("https://example.com", nil)
+	var apiErr *capture.CaptureAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "invalid token" {
+		t.Fatalf("expected invalid token error, got %v", err)
+	}
+	if !errors.Is(err, capture.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+
+	wrongKey := server.Client()
+	wrongKey.Key = "key_2"
+	if _, err := wrongKey.FetchPDF("https://example.com", nil); err == nil || !strings.Contains(err.Error(), "invalid key") {
+		t.Fatalf("expected invalid key error, got %v", err)
+	}
+
+	if _, err := server.Client().FetchPDF("https://example.com", nil); err != nil {
+		t.Fatalf("FetchPDF() with valid credentials: %v", err)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.AddFault(Fault{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Second, Times: 1})
+	client := server.Client()
+
+	_, err := client.FetchImage("https://example.com", nil)
+	var apiErr *capture.CaptureAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter != 2*time.Second {
+		t.Fatalf("expected injected 429, got %v", err)
+	}
+	if _, err := client.FetchImage("https://example.com", nil); err != nil {
+		t.Fatalf("expected fault to be used up, got %v", err)
+	}
+
+	server.AddFault(Fault{
+		Match:      func(r Request) bool { return r.RequestType == capture.RequestTypePDF },
+		StatusCode: http.StatusBadGateway,
+	})
+	if _, err := client.FetchImage("https://example.com", nil); err != nil {
+		t.Fatalf("fault matched the wrong request type: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.FetchPDF("https://example.com", nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+			t.Fatalf("expected injected 502, got %v", err)
+		}
+	}
+	server.ClearFaults()
+
+	server.AddFault(Fault{Latency: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.FetchImageContext(ctx, "https://example.com", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected latency to exceed the deadline, got %v", err)
+	}
+}
+
+func TestServerWithRetryPolicy(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	server.AddFault(Fault{StatusCode: http.StatusServiceUnavailable, Times: 2})
+	client := server.Client(capture.WithRetryPolicy(capture.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	if _, err := client.FetchImage("https://example.com", nil); err != nil {
+		t.Fatalf("expected retries to succeed, got %v", err)
+	}
+
+	var statuses []int
+	for _, req := range server.Requests() {
+		statuses = append(statuses, req.StatusCode)
+	}
+	if len(statuses) != 3 || statuses[0] != 503 || statuses[1] != 503 || statuses[2] != 200 {
+		t.Fatalf("unexpected statuses: %v", statuses)
+	}
+}
@@ -0,0 +1,319 @@
+package capturetest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteMode selects whether a Cassette records or replays traffic.
+type CassetteMode int
+
+const (
+	// CassetteReplay answers requests from the cassette file and fails
+	// requests that were not recorded.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends requests to the real API and records each
+	// request and response. Call Save to write the cassette.
+	CassetteRecord
+)
+
+// ErrNotRecorded is wrapped by the error a replaying Cassette returns for a
+// request it has no recording of.
+var ErrNotRecorded = errors.New("request not recorded in cassette")
+
+// redacted replaces the Authorization header in cassettes. The Sessions API
+// bearer token encodes the API secret.
+const redacted = "REDACTED"
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as stored in a cassette. Its Authorization
+// header is redacted.
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as stored in a cassette. Text bodies are
+// kept in Body; binary bodies such as images are base64 encoded in
+// BodyBase64.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"bodyBase64,omitempty"`
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Cassette is an http.RoundTripper that records Capture API traffic to a
+// file and replays it, for deterministic tests without network access:
+//
+//	cassette, err := capturetest.NewCassette("testdata/render.json", capturetest.CassetteReplay)
+//	c := capture.New(key, secret, capture.WithHTTPClient(cassette.Client()))
+//
+// Render requests are matched by their signed URL, ignoring the key and
+// token path segments so a cassette recorded with real credentials replays
+// with any. Session requests are matched by method, URL and JSON body, i.e.
+// their capture.SessionRequestPreview. Identical requests replay their
+// recordings in order, and the last one repeats once they are used up.
+type Cassette struct {
+	Path string
+	Mode CassetteMode
+	// Transport sends requests while recording. Defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewCassette returns a cassette stored at path. In CassetteReplay mode the
+// file is loaded and must exist; in CassetteRecord mode the cassette starts
+// empty and the file is replaced by Save.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{Path: path, Mode: mode}
+	if mode != CassetteReplay {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	c.interactions = file.Interactions
+	c.used = make([]bool, len(file.Interactions))
+	return c, nil
+}
+
+// Client returns an HTTP client using the cassette, for capture.WithHTTPClient.
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Interactions returns the recorded interactions.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	if c.Mode == CassetteReplay {
+		return c.replay(req, body)
+	}
+	return c.record(req, body)
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	key := interactionKey(req.Method, req.URL.String(), jsonBody(body))
+	c.mu.Lock()
+	match := -1
+	for i, interaction := range c.interactions {
+		if interactionKey(interaction.Request.Method, interaction.Request.URL, interaction.Request.Body) != key {
+			continue
+		}
+		match = i
+		if !c.used[i] {
+			break
+		}
+	}
+	if match >= 0 {
+		c.used[match] = true
+	}
+	c.mu.Unlock()
+
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, renderURL(req.URL.String()))
+	}
+
+	recorded := c.interactions[match].Response
+	respBody := []byte(recorded.Body)
+	if recorded.BodyBase64 != "" {
+		var err error
+		respBody, err = base64.StdEncoding.DecodeString(recorded.BodyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recorded body for %s %s: %w", req.Method, renderURL(req.URL.String()), err)
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+func (c *Cassette) record(req *http.Request, body []byte) (*http.Response, error) {
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	upstream := req.Clone(req.Context())
+	if body != nil {
+		upstream.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := transport.RoundTrip(upstream)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := req.Header.Clone()
+	if header.Get("Authorization") != "" {
+		header.Set("Authorization", redacted)
+	}
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: header,
+			Body:   jsonBody(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+		},
+	}
+	if isTextBody(resp.Header.Get("Content-Type"), respBody) {
+		interaction.Response.Body = string(respBody)
+	} else {
+		interaction.Response.BodyBase64 = base64.StdEncoding.EncodeToString(respBody)
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.used = append(c.used, false)
+	c.mu.Unlock()
+	return resp, nil
+}
+
+// Save writes a recording cassette to Path. It does nothing when replaying.
+func (c *Cassette) Save() error {
+	if c.Mode == CassetteReplay {
+		return nil
+	}
+
+	c.mu.Lock()
+	file := cassetteFile{Interactions: c.interactions}
+	if file.Interactions == nil {
+		file.Interactions = []Interaction{}
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if dir := filepath.Dir(c.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	}
+	tmp := c.Path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp, c.Path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// interactionKey identifies equivalent requests by method, render URL and
+// normalised JSON body.
+func interactionKey(method, rawURL string, body json.RawMessage) string {
+	normalized := []byte(body)
+	var decoded interface{}
+	if len(body) > 0 && json.Unmarshal(body, &decoded) == nil {
+		normalized, _ = json.Marshal(decoded)
+	}
+	return method + " " + renderURL(rawURL) + "\n" + string(normalized)
+}
+
+// renderURL drops the key and token path segments of a render URL, which
+// depend on the credentials rather than the request.
+func renderURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if !strings.HasPrefix(parsed.Path, "/v1/sessions") && len(segments) == 3 {
+		parsed.Path = "/-/-/" + segments[2]
+	}
+	return parsed.String()
+}
+
+// jsonBody stores a request body as JSON: as is when it already is JSON, or
+// as a JSON string otherwise.
+func jsonBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		var buf bytes.Buffer
+		if json.Compact(&buf, body) == nil {
+			return buf.Bytes()
+		}
+	}
+	encoded, _ := json.Marshal(string(body))
+	return encoded
+}
+
+func isTextBody(contentType string, body []byte) bool {
+	if !utf8.Valid(body) {
+		return false
+	}
+	return contentType == "" || strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "json")
+}
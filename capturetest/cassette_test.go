@@ -0,0 +1,137 @@
+package capturetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "flow.json")
+	server := NewServer(WithCredentials("real_key", "real_secret"))
+	ctx := context.Background()
+
+	recorder, err := NewCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatalf("NewCassette() error: %v", err)
+	}
+	recorder.Transport = server.Server.Client().Transport
+	live := server.Client(capture.WithHTTPClient(recorder.Client()))
+
+	image, err := live.FetchImage("https://example.com", capture.RequestOptions{"vw": 320, "vh": 200})
+	if err != nil {
+		t.Fatalf("FetchImage() error: %v", err)
+	}
+	content, err := live.FetchContent("https://example.com", nil)
+	if err != nil {
+		t.Fatalf("FetchContent() error: %v", err)
+	}
+	session, err := live.CreateSessionTypedContext(ctx, &capture.CreateSessionOptions{MaxTtlSeconds: 60})
+	if err != nil {
+		t.Fatalf("CreateSessionTyped() error: %v", err)
+	}
+	if _, err := session.Goto(ctx, capture.GotoAction{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Goto() error: %v", err)
+	}
+	if _, err := session.Goto(ctx, capture.GotoAction{URL: "https://example.com/next"}); err != nil {
+		t.Fatalf("Goto() error: %v", err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "real_secret") || strings.Contains(string(data), "Bearer") {
+		t.Fatal("cassette contains credentials")
+	}
+	if len(recorder.Interactions()) != 5 {
+		t.Fatalf("expected 5 interactions, got %d", len(recorder.Interactions()))
+	}
+
+	player, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatalf("NewCassette(replay) error: %v", err)
+	}
+	replayed := capture.New("other_key", "other_secret", capture.WithHTTPClient(player.Client()))
+	replayed.APIURL, replayed.SessionsURL = live.APIURL, live.SessionsURL
+
+	replayedImage, err := replayed.FetchImage("https://example.com", capture.RequestOptions{"vh": 200, "vw": 320})
+	if err != nil || !bytes.Equal(replayedImage, image) {
+		t.Fatalf("replayed FetchImage() = %d bytes, %v", len(replayedImage), err)
+	}
+	replayedContent, err := replayed.FetchContent("https://example.com", nil)
+	if err != nil || replayedContent.Markdown != content.Markdown {
+		t.Fatalf("replayed FetchContent() = %+v, %v", replayedContent, err)
+	}
+	replayedSession, err := replayed.CreateSessionTypedContext(ctx, &capture.CreateSessionOptions{MaxTtlSeconds: 60})
+	if err != nil || replayedSession.ID != session.ID {
+		t.Fatalf("replayed CreateSessionTyped() = %+v, %v", replayedSession, err)
+	}
+	result, err := replayedSession.Goto(ctx, capture.GotoAction{URL: "https://example.com/next"})
+	if err != nil || result.URL != "https://example.com/next" {
+		t.Fatalf("replayed Goto() = %+v, %v", result, err)
+	}
+
+	if _, err := replayed.FetchPDF("https://example.com", nil); !errors.Is(err, ErrNotRecorded) {
+		t.Fatalf("expected ErrNotRecorded, got %v", err)
+	}
+}
+
+func TestCassetteReplaysRepeatedRequestsInOrder(t *testing.T) {
+	server := NewServer()
+	recorder, _ := NewCassette(filepath.Join(t.TempDir(), "poll.json"), CassetteRecord)
+	recorder.Transport = server.Server.Client().Transport
+	live := server.Client(capture.WithHTTPClient(recorder.Client()))
+	ctx := context.Background()
+
+	session, err := live.CreateSessionTypedContext(ctx, nil)
+	if err != nil {
+		t.Fatalf("CreateSessionTyped() error: %v", err)
+	}
+	if err := session.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error: %v", err)
+	}
+	server.ExpireSession(session.ID)
+	if err := session.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error: %v", err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	server.Close()
+
+	player, err := NewCassette(recorder.Path, CassetteReplay)
+	if err != nil {
+		t.Fatalf("NewCassette(replay) error: %v", err)
+	}
+	replayed := capture.New(DefaultKey, DefaultSecret, capture.WithHTTPClient(player.Client()))
+	replayed.SessionsURL = live.SessionsURL
+
+	var statuses []string
+	for i := 0; i < 3; i++ {
+		got, err := replayed.GetSessionTypedContext(ctx, session.ID)
+		if err != nil {
+			t.Fatalf("GetSessionTyped() error: %v", err)
+		}
+		statuses = append(statuses, got.Status)
+	}
+	if strings.Join(statuses, ",") != "active,expired,expired" {
+		t.Fatalf("unexpected replayed statuses: %v", statuses)
+	}
+}
+
+func TestNewCassetteReplayRequiresFile(t *testing.T) {
+	if _, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), CassetteReplay); err == nil {
+		t.Fatal("expected error for a missing cassette")
+	}
+}
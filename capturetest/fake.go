@@ -0,0 +1,458 @@
+package capturetest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	capture "github.com/techulus/capture-go"
+)
+
+// Call is a method call recorded by a FakeClient. Args holds the arguments
+// after the context, in order.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// FakeClient is a capture.Client for unit tests that do not involve HTTP.
+// Every call is recorded, and calls are answered in memory by the embedded
+// API, so fixtures, HandleAction, ExpireSession and faults apply as they do
+// to a Server. SetError makes a method fail outright.
+//
+//	fake := capturetest.NewFakeClient()
+//	fake.SetContent("https://example.com", capture.ContentResponse{Success: true, Markdown: "# Hi"})
+//	fake.SetError("FetchPDF", errors.New("boom"))
+//
+//	summarize(fake) // func summarize(c capture.Client)
+//
+//	calls := fake.CallsTo("FetchContent")
+type FakeClient struct {
+	*API
+
+	client *capture.Capture
+
+	mu     sync.Mutex
+	calls  []Call
+	errors map[string]error
+}
+
+var _ capture.Client = (*FakeClient)(nil)
+
+// NewFakeClient returns a fake whose API uses options.
+func NewFakeClient(options ...Option) *FakeClient {
+	api := NewAPI(options...)
+	client := capture.New(api.Key, api.Secret, capture.WithHTTPClient(&http.Client{
+		Transport: handlerTransport{api},
+	}))
+	client.APIURL = "http://capture.test"
+	client.EdgeURL = "http://capture.test"
+	client.SessionsURL = "http://capture.test"
+	return &FakeClient{API: api, client: client, errors: make(map[string]error)}
+}
+
+// handlerTransport serves requests with a handler, without a network.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.handler.ServeHTTP(rec, req)
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// SetError makes every call to method, e.g. "FetchImageContext", return err.
+// A nil err removes it.
+func (f *FakeClient) SetError(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errors, method)
+		return
+	}
+	f.errors[method] = err
+}
+
+// Calls returns every recorded call, in order.
+func (f *FakeClient) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the recorded calls to method.
+func (f *FakeClient) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range f.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// ResetCalls forgets the recorded calls.
+func (f *FakeClient) ResetCalls() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// record appends a call and returns the error set for its method.
+func (f *FakeClient) record(method string, args ...interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: method, Args: args})
+	return f.errors[method]
+}
+
+func (f *FakeClient) BuildURL(requestType capture.RequestType, targetURL string, options capture.RequestOptions) (string, error) {
+	if err := f.record("BuildURL", requestType, targetURL, options); err != nil {
+		return "", err
+	}
+	return f.client.BuildURL(requestType, targetURL, options)
+}
+
+func (f *FakeClient) BuildImageURL(targetURL string, options capture.RequestOptions) (string, error) {
+	if err := f.record("BuildImageURL", targetURL, options); err != nil {
+		return "", err
+	}
+	return f.client.BuildImageURL(targetURL, options)
+}
+
+func (f *FakeClient) BuildImageURLTyped(targetURL string, options *capture.ScreenshotOptions) (string, error) {
+	if err := f.record("BuildImageURLTyped", targetURL, options); err != nil {
+		return "", err
+	}
+	return f.client.BuildImageURLTyped(targetURL, options)
+}
+
+func (f *FakeClient) BuildPDFURL(targetURL string, options capture.RequestOptions) (string, error) {
+	if err := f.record("BuildPDFURL", targetURL, options); err != nil {
+		return "", err
+	}
+	return f.client.BuildPDFURL(targetURL, options)
+}
+
+func (f *FakeClient) BuildPDFURLTyped(targetURL string, options *capture.PDFOptions) (string, error) {
+	if err := f.record("BuildPDFURLTyped", targetURL, options); err != nil {
+		return "", err
+	}
+	return f.client.BuildPDFURLTyped(targetURL, options)
+}
+
+func (f *FakeClient) BuildContentURL(targetURL string, options capture.RequestOptions) (string, error) {
+	if err := f.record("BuildContentURL", targetURL, options); err != nil {
+		return "", err
+	}
+	return f.client.BuildContentURL(targetURL, options)
+}
+
+func (f *FakeClient) BuildMetadataURL(targetURL string, options capture.RequestOptions) (string, error) {
+	if err := f.record("BuildMetadataURL", targetURL, options); err != nil {
+		return "", err
+	}
+	return f.client.BuildMetadataURL(targetURL, options)
+}
+
+func (f *FakeClient) BuildAnimatedURL(targetURL string, options capture.RequestOptions) (string, error) {
+	if err := f.record("BuildAnimatedURL", targetURL, options); err != nil {
+		return "", err
+	}
+	return f.client.BuildAnimatedURL(targetURL, options)
+}
+
+func (f *FakeClient) FetchImage(targetURL string, options capture.RequestOptions) ([]byte, error) {
+	if err := f.record("FetchImage", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchImage(targetURL, options)
+}
+
+func (f *FakeClient) FetchImageContext(ctx context.Context, targetURL string, options capture.RequestOptions) ([]byte, error) {
+	if err := f.record("FetchImageContext", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchImageContext(ctx, targetURL, options)
+}
+
+func (f *FakeClient) FetchImageTyped(targetURL string, options *capture.ScreenshotOptions) ([]byte, error) {
+	if err := f.record("FetchImageTyped", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchImageTyped(targetURL, options)
+}
+
+func (f *FakeClient) FetchImageTypedContext(ctx context.Context, targetURL string, options *capture.ScreenshotOptions) ([]byte, error) {
+	if err := f.record("FetchImageTypedContext", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchImageTypedContext(ctx, targetURL, options)
+}
+
+func (f *FakeClient) FetchImageTo(w io.Writer, targetURL string, options capture.RequestOptions) (int64, error) {
+	if err := f.record("FetchImageTo", w, targetURL, options); err != nil {
+		return 0, err
+	}
+	return f.client.FetchImageTo(w, targetURL, options)
+}
+
+func (f *FakeClient) FetchImageToContext(ctx context.Context, w io.Writer, targetURL string, options capture.RequestOptions) (int64, error) {
+	if err := f.record("FetchImageToContext", w, targetURL, options); err != nil {
+		return 0, err
+	}
+	return f.client.FetchImageToContext(ctx, w, targetURL, options)
+}
+
+func (f *FakeClient) FetchPDF(targetURL string, options capture.RequestOptions) ([]byte, error) {
+	if err := f.record("FetchPDF", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchPDF(targetURL, options)
+}
+
+func (f *FakeClient) FetchPDFContext(ctx context.Context, targetURL string, options capture.RequestOptions) ([]byte, error) {
+	if err := f.record("FetchPDFContext", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchPDFContext(ctx, targetURL, options)
+}
+
+func (f *FakeClient) FetchPDFTyped(targetURL string, options *capture.PDFOptions) ([]byte, error) {
+	if err := f.record("FetchPDFTyped", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchPDFTyped(targetURL, options)
+}
+
+func (f *FakeClient) FetchPDFTypedContext(ctx context.Context, targetURL string, options *capture.PDFOptions) ([]byte, error) {
+	if err := f.record("FetchPDFTypedContext", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchPDFTypedContext(ctx, targetURL, options)
+}
+
+func (f *FakeClient) FetchPDFTo(w io.Writer, targetURL string, options capture.RequestOptions) (int64, error) {
+	if err := f.record("FetchPDFTo", w, targetURL, options); err != nil {
+		return 0, err
+	}
+	return f.client.FetchPDFTo(w, targetURL, options)
+}
+
+func (f *FakeClient) FetchPDFToContext(ctx context.Context, w io.Writer, targetURL string, options capture.RequestOptions) (int64, error) {
+	if err := f.record("FetchPDFToContext", w, targetURL, options); err != nil {
+		return 0, err
+	}
+	return f.client.FetchPDFToContext(ctx, w, targetURL, options)
+}
+
+func (f *FakeClient) FetchContent(targetURL string, options capture.RequestOptions) (*capture.ContentResponse, error) {
+	if err := f.record("FetchContent", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchContent(targetURL, options)
+}
+
+func (f *FakeClient) FetchContentContext(ctx context.Context, targetURL string, options capture.RequestOptions) (*capture.ContentResponse, error) {
+	if err := f.record("FetchContentContext", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchContentContext(ctx, targetURL, options)
+}
+
+func (f *FakeClient) FetchMetadata(targetURL string, options capture.RequestOptions) (*capture.MetadataResponse, error) {
+	if err := f.record("FetchMetadata", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchMetadata(targetURL, options)
+}
+
+func (f *FakeClient) FetchMetadataContext(ctx context.Context, targetURL string, options capture.RequestOptions) (*capture.MetadataResponse, error) {
+	if err := f.record("FetchMetadataContext", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchMetadataContext(ctx, targetURL, options)
+}
+
+func (f *FakeClient) FetchAnimated(targetURL string, options capture.RequestOptions) ([]byte, error) {
+	if err := f.record("FetchAnimated", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchAnimated(targetURL, options)
+}
+
+func (f *FakeClient) FetchAnimatedContext(ctx context.Context, targetURL string, options capture.RequestOptions) ([]byte, error) {
+	if err := f.record("FetchAnimatedContext", targetURL, options); err != nil {
+		return nil, err
+	}
+	return f.client.FetchAnimatedContext(ctx, targetURL, options)
+}
+
+func (f *FakeClient) FetchAnimatedTo(w io.Writer, targetURL string, options capture.RequestOptions) (int64, error) {
+	if err := f.record("FetchAnimatedTo", w, targetURL, options); err != nil {
+		return 0, err
+	}
+	return f.client.FetchAnimatedTo(w, targetURL, options)
+}
+
+func (f *FakeClient) FetchAnimatedToContext(ctx context.Context, w io.Writer, targetURL string, options capture.RequestOptions) (int64, error) {
+	if err := f.record("FetchAnimatedToContext", w, targetURL, options); err != nil {
+		return 0, err
+	}
+	return f.client.FetchAnimatedToContext(ctx, w, targetURL, options)
+}
+
+func (f *FakeClient) BuildCreateSessionRequest(options *capture.CreateSessionOptions) capture.SessionRequestPreview {
+	_ = f.record("BuildCreateSessionRequest", options)
+	return f.client.BuildCreateSessionRequest(options)
+}
+
+func (f *FakeClient) BuildGetSessionRequest(sessionID string) capture.SessionRequestPreview {
+	_ = f.record("BuildGetSessionRequest", sessionID)
+	return f.client.BuildGetSessionRequest(sessionID)
+}
+
+func (f *FakeClient) BuildCloseSessionRequest(sessionID string) capture.SessionRequestPreview {
+	_ = f.record("BuildCloseSessionRequest", sessionID)
+	return f.client.BuildCloseSessionRequest(sessionID)
+}
+
+func (f *FakeClient) BuildExecuteActionRequest(sessionID, actionType string, payload capture.SessionActionPayload) capture.SessionRequestPreview {
+	_ = f.record("BuildExecuteActionRequest", sessionID, actionType, payload)
+	return f.client.BuildExecuteActionRequest(sessionID, actionType, payload)
+}
+
+func (f *FakeClient) CreateSession(options *capture.CreateSessionOptions) (capture.SessionResponse, error) {
+	if err := f.record("CreateSession", options); err != nil {
+		return nil, err
+	}
+	return f.client.CreateSession(options)
+}
+
+func (f *FakeClient) CreateSessionContext(ctx context.Context, options *capture.CreateSessionOptions) (capture.SessionResponse, error) {
+	if err := f.record("CreateSessionContext", options); err != nil {
+		return nil, err
+	}
+	return f.client.CreateSessionContext(ctx, options)
+}
+
+func (f *FakeClient) CreateSessionTyped(options *capture.CreateSessionOptions) (*capture.Session, error) {
+	if err := f.record("CreateSessionTyped", options); err != nil {
+		return nil, err
+	}
+	return f.bind(f.client.CreateSessionTyped(options))
+}
+
+func (f *FakeClient) CreateSessionTypedContext(ctx context.Context, options *capture.CreateSessionOptions) (*capture.Session, error) {
+	if err := f.record("CreateSessionTypedContext", options); err != nil {
+		return nil, err
+	}
+	return f.bind(f.client.CreateSessionTypedContext(ctx, options))
+}
+
+func (f *FakeClient) GetSession(sessionID string) (capture.SessionResponse, error) {
+	if err := f.record("GetSession", sessionID); err != nil {
+		return nil, err
+	}
+	return f.client.GetSession(sessionID)
+}
+
+func (f *FakeClient) GetSessionContext(ctx context.Context, sessionID string) (capture.SessionResponse, error) {
+	if err := f.record("GetSessionContext", sessionID); err != nil {
+		return nil, err
+	}
+	return f.client.GetSessionContext(ctx, sessionID)
+}
+
+func (f *FakeClient) GetSessionTyped(sessionID string) (*capture.Session, error) {
+	if err := f.record("GetSessionTyped", sessionID); err != nil {
+		return nil, err
+	}
+	return f.bind(f.client.GetSessionTyped(sessionID))
+}
+
+func (f *FakeClient) GetSessionTypedContext(ctx context.Context, sessionID string) (*capture.Session, error) {
+	if err := f.record("GetSessionTypedContext", sessionID); err != nil {
+		return nil, err
+	}
+	return f.bind(f.client.GetSessionTypedContext(ctx, sessionID))
+}
+
+func (f *FakeClient) CloseSession(sessionID string) (capture.SessionResponse, error) {
+	if err := f.record("CloseSession", sessionID); err != nil {
+		return nil, err
+	}
+	return f.client.CloseSession(sessionID)
+}
+
+func (f *FakeClient) CloseSessionContext(ctx context.Context, sessionID string) (capture.SessionResponse, error) {
+	if err := f.record("CloseSessionContext", sessionID); err != nil {
+		return nil, err
+	}
+	return f.client.CloseSessionContext(ctx, sessionID)
+}
+
+func (f *FakeClient) CloseSessionTyped(sessionID string) (*capture.Session, error) {
+	if err := f.record("CloseSessionTyped", sessionID); err != nil {
+		return nil, err
+	}
+	return f.bind(f.client.CloseSessionTyped(sessionID))
+}
+
+func (f *FakeClient) CloseSessionTypedContext(ctx context.Context, sessionID string) (*capture.Session, error) {
+	if err := f.record("CloseSessionTypedContext", sessionID); err != nil {
+		return nil, err
+	}
+	return f.bind(f.client.CloseSessionTypedContext(ctx, sessionID))
+}
+
+func (f *FakeClient) ExecuteAction(sessionID, actionType string, payload capture.SessionActionPayload) (capture.SessionActionResponse, error) {
+	if err := f.record("ExecuteAction", sessionID, actionType, payload); err != nil {
+		return nil, err
+	}
+	return f.client.ExecuteAction(sessionID, actionType, payload)
+}
+
+func (f *FakeClient) ExecuteActionContext(ctx context.Context, sessionID, actionType string, payload capture.SessionActionPayload) (capture.SessionActionResponse, error) {
+	if err := f.record("ExecuteActionContext", sessionID, actionType, payload); err != nil {
+		return nil, err
+	}
+	return f.client.ExecuteActionContext(ctx, sessionID, actionType, payload)
+}
+
+func (f *FakeClient) ExecuteActionTyped(sessionID, actionType string, payload capture.SessionActionPayload) (*capture.ActionResult, error) {
+	if err := f.record("ExecuteActionTyped", sessionID, actionType, payload); err != nil {
+		return nil, err
+	}
+	return f.client.ExecuteActionTyped(sessionID, actionType, payload)
+}
+
+func (f *FakeClient) ExecuteActionTypedContext(ctx context.Context, sessionID, actionType string, payload capture.SessionActionPayload) (*capture.ActionResult, error) {
+	if err := f.record("ExecuteActionTypedContext", sessionID, actionType, payload); err != nil {
+		return nil, err
+	}
+	return f.client.ExecuteActionTypedContext(ctx, sessionID, actionType, payload)
+}
+
+// AttachSession returns a handle whose actions go through the fake, so
+// they are recorded as ExecuteActionContext calls.
+func (f *FakeClient) AttachSession(sessionID string) *capture.Session {
+	_ = f.record("AttachSession", sessionID)
+	return f.client.AttachSession(sessionID).Bind(f)
+}
+
+// bind routes the requests of a returned session through the fake.
+func (f *FakeClient) bind(session *capture.Session, err error) (*capture.Session, error) {
+	if session == nil || err != nil {
+		return session, err
+	}
+	return session.Bind(f), nil
+}
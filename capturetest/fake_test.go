@@ -0,0 +1,127 @@
+package capturetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func TestFakeClientRecordsCalls(t *testing.T) {
+	fake := NewFakeClient()
+	fake.SetContent("https://example.com", capture.ContentResponse{Success: true, Markdown: "# Canned"})
+
+	var client capture.Client = fake
+	content, err := client.FetchContent("https://example.com", capture.RequestOptions{"delay": 1})
+	if err != nil || content.Markdown != "# Canned" {
+		t.Fatalf("FetchContent() = %+v, %v", content, err)
+	}
+	image, err := client.FetchImageContext(context.Background(), "https://example.com", nil)
+	if err != nil || !bytes.HasPrefix(image, []byte("\x89PNG")) {
+		t.Fatalf("FetchImageContext() = %d bytes, %v", len(image), err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 2 || calls[0].Method != "FetchContent" || calls[1].Method != "FetchImageContext" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	if calls[0].Args[0] != "https://example.com" || calls[0].Args[1].(capture.RequestOptions)["delay"] != 1 {
+		t.Fatalf("unexpected FetchContent args: %+v", calls[0].Args)
+	}
+	if got := fake.Requests(); len(got) != 2 || got[0].RequestType != "content" {
+		t.Fatalf("unexpected API requests: %+v", got)
+	}
+
+	fake.ResetCalls()
+	if len(fake.Calls()) != 0 {
+		t.Fatal("expected ResetCalls to forget calls")
+	}
+}
+
+func TestFakeClientSetError(t *testing.T) {
+	fake := NewFakeClient()
+	boom := errors.New("boom")
+	fake.SetError("FetchPDF", boom)
+
+	if _, err := fake.FetchPDF("https://example.com", nil); !errors.Is(err, boom) {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+	if len(fake.Requests()) != 0 {
+		t.Fatal("expected injected error to skip the API")
+	}
+	if _, err := fake.FetchPDFContext(context.Background(), "https://example.com", nil); err != nil {
+		t.Fatalf("FetchPDFContext() error: %v", err)
+	}
+
+	fake.SetError("FetchPDF", nil)
+	if _, err := fake.FetchPDF("https://example.com", nil); err != nil {
+		t.Fatalf("FetchPDF() after clearing error: %v", err)
+	}
+	if len(fake.CallsTo("FetchPDF")) != 2 {
+		t.Fatalf("expected 2 FetchPDF calls, got %+v", fake.CallsTo("FetchPDF"))
+	}
+}
+
+func TestFakeClientSessions(t *testing.T) {
+	fake := NewFakeClient()
+	ctx := context.Background()
+	fake.HandleAction("evaluate", func(sessionID string, payload capture.SessionActionPayload) (capture.SessionActionResponse, error) {
+		return capture.SessionActionResponse{"success": true, "result": payload["expression"]}, nil
+	})
+
+	session, err := fake.CreateSessionTypedContext(ctx, nil)
+	if err != nil {
+		t.Fatalf("CreateSessionTyped() error: %v", err)
+	}
+	result, err := session.Evaluate(ctx, "document.title")
+	if err != nil || result.Result != "document.title" {
+		t.Fatalf("Evaluate() = %+v, %v", result, err)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	actions := fake.CallsTo("ExecuteActionContext")
+	if len(actions) != 1 || actions[0].Args[0] != session.ID || actions[0].Args[1] != "evaluate" {
+		t.Fatalf("unexpected action calls: %+v", actions)
+	}
+	if len(fake.CallsTo("CloseSessionContext")) != 1 {
+		t.Fatalf("expected Close to go through the fake, got %+v", fake.Calls())
+	}
+
+	attached := fake.AttachSession(session.ID)
+	if _, err := attached.Goto(ctx, capture.GotoAction{URL: "https://example.com"}); err == nil {
+		t.Fatal("expected Goto on a closed session to fail")
+	}
+	if len(fake.CallsTo("ExecuteActionContext")) != 2 {
+		t.Fatalf("expected attached session actions to be recorded, got %+v", fake.Calls())
+	}
+}
+
+func ExampleFakeClient() {
+	fake := NewFakeClient()
+	fake.SetMetadata("https://example.com", map[string]interface{}{"title": "Example Domain"})
+	fake.SetError("FetchPDF", errors.New("boom"))
+
+	archive := func(c capture.Client) error {
+		meta, err := c.FetchMetadata("https://example.com", nil)
+		if err != nil {
+			return err
+		}
+		fmt.Println(meta.Metadata["title"])
+		_, err = c.FetchPDF("https://example.com", nil)
+		return err
+	}
+	fmt.Println(archive(fake))
+
+	for _, call := range fake.CallsTo("FetchMetadata") {
+		fmt.Println(call.Args...)
+	}
+	// Output:
+	// Example Domain
+	// boom
+	// https://example.com map[]
+}
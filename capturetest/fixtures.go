@@ -0,0 +1,168 @@
+package capturetest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+
+	capture "github.com/techulus/capture-go"
+)
+
+const (
+	defaultWidth     = 1440
+	defaultHeight    = 900
+	maxDimension     = 4096
+	placeholderTitle = "Capture placeholder"
+)
+
+// defaultFixture generates a placeholder response for a render request
+// without a fixture: an image sized by vw and vh, a one-page PDF, a short
+// GIF, or canned content and metadata describing targetURL.
+func defaultFixture(requestType capture.RequestType, targetURL string, options url.Values) (Fixture, error) {
+	width := dimension(options.Get("vw"), defaultWidth)
+	height := dimension(options.Get("vh"), defaultHeight)
+
+	switch requestType {
+	case capture.RequestTypeImage:
+		format := strings.ToLower(options.Get("type"))
+		if format != "jpeg" && format != "jpg" {
+			format = "png"
+		}
+		body, err := placeholderImage(targetURL, format, width, height)
+		if err != nil {
+			return Fixture{}, err
+		}
+		contentType := "image/png"
+		if format != "png" {
+			contentType = "image/jpeg"
+		}
+		return Fixture{ContentType: contentType, Body: body}, nil
+	case capture.RequestTypePDF:
+		return Fixture{ContentType: "application/pdf", Body: placeholderPDF(targetURL)}, nil
+	case capture.RequestTypeAnimated:
+		body, err := placeholderGIF(targetURL, width/4, height/4)
+		if err != nil {
+			return Fixture{}, err
+		}
+		return Fixture{ContentType: "image/gif", Body: body}, nil
+	case capture.RequestTypeContent:
+		return jsonFixture(capture.ContentResponse{
+			Success:     true,
+			HTML:        fmt.Sprintf("<html><head><title>%s</title></head><body><h1>%s</h1><p>Placeholder content for %s</p></body></html>", placeholderTitle, placeholderTitle, targetURL),
+			TextContent: fmt.Sprintf("%s\nPlaceholder content for %s", placeholderTitle, targetURL),
+			Markdown:    fmt.Sprintf("# %s\n\nPlaceholder content for %s\n", placeholderTitle, targetURL),
+		})
+	case capture.RequestTypeMetadata:
+		return jsonFixture(capture.MetadataResponse{
+			Success: true,
+			Metadata: map[string]interface{}{
+				"title":       placeholderTitle,
+				"description": "Placeholder metadata for " + targetURL,
+				"url":         targetURL,
+				"lang":        "en",
+			},
+		})
+	}
+	return Fixture{}, fmt.Errorf("unknown request type %q", requestType)
+}
+
+func jsonFixture(value interface{}) (Fixture, error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return Fixture{}, err
+	}
+	return Fixture{ContentType: "application/json", Body: body}, nil
+}
+
+func dimension(value string, fallback int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	if n > maxDimension {
+		return maxDimension
+	}
+	return n
+}
+
+// placeholderColor derives a stable colour from seed, so renders of
+// different URLs are distinguishable.
+func placeholderColor(seed string) color.RGBA {
+	sum := md5.Sum([]byte(seed))
+	return color.RGBA{R: 64 + sum[0]%128, G: 64 + sum[1]%128, B: 64 + sum[2]%128, A: 255}
+}
+
+func placeholderImage(seed, format string, width, height int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: placeholderColor(seed)}, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode placeholder image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func placeholderGIF(seed string, width, height int) ([]byte, error) {
+	width, height = max(width, 1), max(height, 1)
+	animation := &gif.GIF{}
+	for _, c := range []color.Color{placeholderColor(seed), color.White} {
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), palette.Plan9)
+		draw.Draw(frame, frame.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 50)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		return nil, fmt.Errorf("failed to encode placeholder GIF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// placeholderPDF returns a valid one-page PDF that prints targetURL.
+func placeholderPDF(targetURL string) []byte {
+	escape := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", " ", "\n", " ")
+	text := fmt.Sprintf("BT /F1 18 Tf 72 720 Td (%s) Tj 0 -28 Td /F1 11 Tf (%s) Tj ET",
+		placeholderTitle, escape.Replace(targetURL))
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(text), text),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
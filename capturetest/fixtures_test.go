@@ -0,0 +1,70 @@
+package capturetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func TestDefaultFixtures(t *testing.T) {
+	target := "https://example.com/(page)"
+
+	image, err := defaultFixture(capture.RequestTypeImage, target, url.Values{"vw": {"320"}, "vh": {"240"}})
+	if err != nil || image.ContentType != "image/png" {
+		t.Fatalf("image fixture = %q, %v", image.ContentType, err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(image.Body))
+	if err != nil || decoded.Bounds().Dx() != 320 || decoded.Bounds().Dy() != 240 {
+		t.Fatalf("unexpected PNG: %v, %v", decoded.Bounds(), err)
+	}
+
+	jpg, err := defaultFixture(capture.RequestTypeImage, target, url.Values{"type": {"jpeg"}, "vw": {"99999"}, "vh": {"10"}})
+	if err != nil || jpg.ContentType != "image/jpeg" {
+		t.Fatalf("jpeg fixture = %q, %v", jpg.ContentType, err)
+	}
+	if config, err := jpeg.DecodeConfig(bytes.NewReader(jpg.Body)); err != nil || config.Width != maxDimension {
+		t.Fatalf("unexpected JPEG: %+v, %v", config, err)
+	}
+
+	animated, err := defaultFixture(capture.RequestTypeAnimated, target, nil)
+	if err != nil {
+		t.Fatalf("animated fixture error: %v", err)
+	}
+	if decoded, err := gif.DecodeAll(bytes.NewReader(animated.Body)); err != nil || len(decoded.Image) != 2 {
+		t.Fatalf("unexpected GIF: %v", err)
+	}
+
+	pdf, err := defaultFixture(capture.RequestTypePDF, target, nil)
+	if err != nil || pdf.ContentType != "application/pdf" {
+		t.Fatalf("pdf fixture = %q, %v", pdf.ContentType, err)
+	}
+	body := string(pdf.Body)
+	if !strings.HasPrefix(body, "%PDF-1.4\n") || !strings.HasSuffix(body, "%%EOF\n") || !strings.Contains(body, `example.com/\(page\)`) {
+		t.Fatalf("unexpected PDF:\n%s", body)
+	}
+	xref := strings.Index(body, "xref\n")
+	if !strings.Contains(body, "startxref\n"+strconv.Itoa(xref)+"\n") {
+		t.Fatalf("startxref does not point at the xref table (%d)", xref)
+	}
+
+	content, err := defaultFixture(capture.RequestTypeContent, target, nil)
+	if err != nil {
+		t.Fatalf("content fixture error: %v", err)
+	}
+	var contentResp capture.ContentResponse
+	if err := json.Unmarshal(content.Body, &contentResp); err != nil || !strings.Contains(contentResp.Markdown, target) {
+		t.Fatalf("unexpected content: %+v, %v", contentResp, err)
+	}
+
+	if _, err := defaultFixture("video", target, nil); err == nil {
+		t.Fatal("expected error for an unknown request type")
+	}
+}
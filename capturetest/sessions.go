@@ -0,0 +1,340 @@
+package capturetest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	capture "github.com/techulus/capture-go"
+)
+
+// DefaultMaxTtlSeconds is the lifetime of sessions created without
+// MaxTtlSeconds.
+const DefaultMaxTtlSeconds = 300
+
+// ActionFunc handles a session action. A returned error is sent as a 400
+// response, which the client reports as capture.ErrInvalidAction.
+type ActionFunc func(sessionID string, payload capture.SessionActionPayload) (capture.SessionActionResponse, error)
+
+type fakeSession struct {
+	id         string
+	options    capture.CreateSessionOptions
+	status     string
+	connectURL string
+	createdAt  time.Time
+	expiresAt  time.Time
+	pageURL    string
+	cookies    []interface{}
+
+	actionCount        int
+	actionSuccessCount int
+	actionErrorCount   int
+}
+
+// currentStatus marks active sessions past their TTL as expired.
+func (s *fakeSession) currentStatus(now time.Time) string {
+	if s.status == "active" && !now.Before(s.expiresAt) {
+		s.status = "expired"
+	}
+	return s.status
+}
+
+func (s *fakeSession) object(now time.Time) map[string]interface{} {
+	object := map[string]interface{}{
+		"id":                 s.id,
+		"status":             s.currentStatus(now),
+		"cdp":                s.options.CDP,
+		"proxy":              s.options.Proxy,
+		"bypassBotDetection": s.options.BypassBotDetection,
+		"maxTtlSeconds":      s.options.MaxTtlSeconds,
+		"createdAt":          s.createdAt.Format(time.RFC3339Nano),
+		"startedAt":          s.createdAt.Format(time.RFC3339Nano),
+		"expiresAt":          s.expiresAt.Format(time.RFC3339Nano),
+		"actionCount":        s.actionCount,
+		"actionSuccessCount": s.actionSuccessCount,
+		"actionErrorCount":   s.actionErrorCount,
+	}
+	if s.connectURL != "" {
+		object["connectUrl"] = s.connectURL
+	}
+	return object
+}
+
+// HandleAction replaces the built-in handling of actionType, or adds an
+// action the fake does not know. The built-in actions are goto, click, type,
+// waitForSelector, evaluate (which returns a null result), screenshot,
+// getCookies and setCookies.
+func (a *API) HandleAction(actionType string, handler ActionFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.actions[actionType] = handler
+}
+
+// Session returns the current state of a session created on the fake.
+func (a *API) Session(sessionID string) (*capture.Session, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	session, ok := a.sessions[sessionID]
+	if !ok {
+		return nil, false
+	}
+	decoded, err := capture.SessionResponse{"session": session.object(time.Now())}.Session()
+	if err != nil {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// ExpireSession ends a session as if it had reached its TTL.
+func (a *API) ExpireSession(sessionID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	session, ok := a.sessions[sessionID]
+	if !ok {
+		return false
+	}
+	session.expiresAt = time.Now()
+	session.currentStatus(session.expiresAt)
+	return true
+}
+
+func (a *API) parseSessionRequest(r *http.Request, req *Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/sessions"), "/")
+	if rest != "" {
+		req.SessionID, _, _ = strings.Cut(rest, "/")
+	}
+	if r.Body != nil {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err == nil {
+			req.Body = body
+		}
+	}
+	if strings.HasSuffix(rest, "/actions") {
+		req.ActionType, _ = req.Body["type"].(string)
+	}
+}
+
+func (a *API) serveSession(w http.ResponseWriter, r *http.Request, req Request) {
+	if r.Header.Get("Authorization") != "Bearer "+a.bearerToken() {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/sessions"), "/")
+	parts := strings.Split(rest, "/")
+	switch {
+	case rest == "" && r.Method == http.MethodPost:
+		a.createSession(w, r, req)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		a.withSession(w, req.SessionID, func(session *fakeSession, now time.Time) (int, interface{}) {
+			return http.StatusOK, map[string]interface{}{"success": true, "session": session.object(now)}
+		})
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		a.withSession(w, req.SessionID, func(session *fakeSession, now time.Time) (int, interface{}) {
+			if session.currentStatus(now) == "active" {
+				session.status = "closed"
+			}
+			return http.StatusOK, map[string]interface{}{"success": true, "session": session.object(now)}
+		})
+	case len(parts) == 2 && parts[1] == "actions" && r.Method == http.MethodPost:
+		a.executeAction(w, req)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (a *API) createSession(w http.ResponseWriter, r *http.Request, req Request) {
+	var options capture.CreateSessionOptions
+	if req.Body != nil {
+		data, _ := json.Marshal(req.Body)
+		if err := json.Unmarshal(data, &options); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid session options: "+err.Error())
+			return
+		}
+	}
+	if options.CDP && (options.Proxy || options.BypassBotDetection) {
+		writeError(w, http.StatusBadRequest, "cdp cannot be combined with proxy or bypassBotDetection")
+		return
+	}
+	if options.MaxTtlSeconds <= 0 {
+		options.MaxTtlSeconds = DefaultMaxTtlSeconds
+	}
+
+	now := time.Now()
+	a.mu.Lock()
+	a.nextID++
+	session := &fakeSession{
+		id:        fmt.Sprintf("sess_test_%d", a.nextID),
+		options:   options,
+		status:    "active",
+		createdAt: now,
+		expiresAt: now.Add(time.Duration(options.MaxTtlSeconds) * time.Second),
+	}
+	if options.CDP {
+		// The fake does not run a browser; the URL only has the right shape.
+		session.connectURL = "ws://" + r.Host + "/cdp/" + session.id
+	}
+	a.sessions[session.id] = session
+	object := session.object(now)
+	a.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "session": object})
+}
+
+// withSession runs fn with the session locked, answering 404 for unknown
+// sessions.
+func (a *API) withSession(w http.ResponseWriter, sessionID string, fn func(*fakeSession, time.Time) (int, interface{})) {
+	a.mu.Lock()
+	session, ok := a.sessions[sessionID]
+	if !ok {
+		a.mu.Unlock()
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	status, body := fn(session, time.Now())
+	a.mu.Unlock()
+	writeJSON(w, status, body)
+}
+
+func (a *API) executeAction(w http.ResponseWriter, req Request) {
+	payload := capture.SessionActionPayload{}
+	if raw, ok := req.Body["payload"].(map[string]interface{}); ok {
+		payload = raw
+	}
+
+	a.mu.Lock()
+	session, ok := a.sessions[req.SessionID]
+	if !ok {
+		a.mu.Unlock()
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	switch session.currentStatus(time.Now()) {
+	case "expired":
+		a.mu.Unlock()
+		writeJSON(w, http.StatusGone, map[string]interface{}{"success": false, "error": "session expired", "code": "session_expired"})
+		return
+	case "closed":
+		a.mu.Unlock()
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "session is closed", "code": "session_closed"})
+		return
+	}
+	handler, custom := a.actions[req.ActionType]
+	a.mu.Unlock()
+
+	// Custom handlers run unlocked so they may call back into the API.
+	var (
+		response capture.SessionActionResponse
+		err      error
+	)
+	if custom {
+		response, err = handler(req.SessionID, payload)
+	} else {
+		a.mu.Lock()
+		response, err = session.builtinAction(req.ActionType, payload)
+		a.mu.Unlock()
+	}
+
+	a.mu.Lock()
+	session.actionCount++
+	if err != nil {
+		session.actionErrorCount++
+	} else {
+		session.actionSuccessCount++
+	}
+	a.mu.Unlock()
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if response == nil {
+		response = capture.SessionActionResponse{}
+	}
+	if _, ok := response["success"]; !ok {
+		response["success"] = true
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *fakeSession) builtinAction(actionType string, payload capture.SessionActionPayload) (capture.SessionActionResponse, error) {
+	switch actionType {
+	case capture.ActionGoto:
+		target, err := requiredString(payload, "url")
+		if err != nil {
+			return nil, err
+		}
+		s.pageURL = target
+		return capture.SessionActionResponse{"url": target, "title": placeholderTitle}, nil
+	case capture.ActionClick, capture.ActionWaitForSelector:
+		if _, err := requiredString(payload, "selector"); err != nil {
+			return nil, err
+		}
+		return capture.SessionActionResponse{}, nil
+	case capture.ActionType:
+		if _, err := requiredString(payload, "selector"); err != nil {
+			return nil, err
+		}
+		if _, err := requiredString(payload, "text"); err != nil {
+			return nil, err
+		}
+		return capture.SessionActionResponse{}, nil
+	case capture.ActionEvaluate:
+		if _, err := requiredString(payload, "expression"); err != nil {
+			return nil, err
+		}
+		return capture.SessionActionResponse{"result": nil}, nil
+	case capture.ActionScreenshot:
+		image, err := placeholderImage(s.pageURL, "png", defaultWidth, defaultHeight)
+		if err != nil {
+			return nil, err
+		}
+		return capture.SessionActionResponse{"data": base64.StdEncoding.EncodeToString(image), "contentType": "image/png"}, nil
+	case capture.ActionGetCookies:
+		cookies := s.cookies
+		if cookies == nil {
+			cookies = []interface{}{}
+		}
+		return capture.SessionActionResponse{"cookies": cookies}, nil
+	case capture.ActionSetCookies:
+		cookies, ok := payload["cookies"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("payload.cookies must be a list")
+		}
+		s.setCookies(cookies)
+		return capture.SessionActionResponse{}, nil
+	}
+	return nil, fmt.Errorf("unknown action type %q", actionType)
+}
+
+// setCookies replaces cookies with the same name, domain and path.
+func (s *fakeSession) setCookies(cookies []interface{}) {
+	key := func(cookie interface{}) string {
+		fields, _ := cookie.(map[string]interface{})
+		return fmt.Sprint(fields["name"], "\x00", fields["domain"], "\x00", fields["path"])
+	}
+	for _, cookie := range cookies {
+		replaced := false
+		for i, existing := range s.cookies {
+			if key(existing) == key(cookie) {
+				s.cookies[i] = cookie
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.cookies = append(s.cookies, cookie)
+		}
+	}
+}
+
+func requiredString(payload capture.SessionActionPayload, key string) (string, error) {
+	value, ok := payload[key].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("payload.%s is required", key)
+	}
+	return value, nil
+}
@@ -0,0 +1,155 @@
+package capturetest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func TestServerSessionLifecycle(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	session, err := client.CreateSessionTypedContext(ctx, &capture.CreateSessionOptions{MaxTtlSeconds: 60})
+	if err != nil {
+		t.Fatalf("CreateSessionTyped() error: %v", err)
+	}
+	if session.ID == "" || session.Status != "active" || session.MaxTtlSeconds != 60 || session.ExpiresAt.IsZero() {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+
+	if _, err := session.Goto(ctx, capture.GotoAction{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Goto() error: %v", err)
+	}
+	shot, err := session.Screenshot(ctx, capture.ScreenshotAction{})
+	if err != nil {
+		t.Fatalf("Screenshot() error: %v", err)
+	}
+	if image, err := shot.Image(); err != nil || len(image) == 0 {
+		t.Fatalf("Screenshot().Image() = %d bytes, %v", len(image), err)
+	}
+	if err := session.SetCookies(ctx, []capture.Cookie{{Name: "sid", Value: "1", Domain: "example.com", Path: "/"}}); err != nil {
+		t.Fatalf("SetCookies() error: %v", err)
+	}
+	cookies, err := session.Cookies(ctx)
+	if err != nil || len(cookies) != 1 || cookies[0].Name != "sid" {
+		t.Fatalf("Cookies() = %+v, %v", cookies, err)
+	}
+
+	if _, err := session.Click(ctx, capture.ClickAction{}); !errors.Is(err, capture.ErrInvalidAction) {
+		t.Fatalf("expected ErrInvalidAction for a click without selector, got %v", err)
+	}
+
+	state, ok := server.Session(session.ID)
+	if !ok || state.ActionCount != 5 || state.ActionErrorCount != 1 {
+		t.Fatalf("unexpected server state: %+v", state)
+	}
+
+	if err := session.CloseContext(ctx); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if state, _ := server.Session(session.ID); state.Status != "closed" {
+		t.Fatalf("expected closed session, got %q", state.Status)
+	}
+	if _, err := client.ExecuteAction(session.ID, capture.ActionGoto, capture.SessionActionPayload{"url": "https://example.com"}); err == nil {
+		t.Fatal("expected actions on a closed session to fail")
+	}
+
+	var actions []string
+	for _, req := range server.Requests() {
+		if req.ActionType != "" {
+			actions = append(actions, req.ActionType)
+		}
+	}
+	if len(actions) != 6 || actions[0] != capture.ActionGoto || actions[2] != capture.ActionSetCookies {
+		t.Fatalf("unexpected recorded actions: %v", actions)
+	}
+}
+
+func TestServerSessionErrors(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	if _, err := client.GetSessionContext(ctx, "sess_missing"); !errors.Is(err, capture.ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+	if _, err := client.CreateSessionContext(ctx, &capture.CreateSessionOptions{CDP: true, Proxy: true}); err == nil {
+		t.Fatal("expected CDP with proxy to be rejected")
+	}
+
+	unauthorized := server.Client()
+	unauthorized.Secret = "wrong"
+	if _, err := unauthorized.CreateSessionContext(ctx, nil); !errors.Is(err, capture.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+
+	session, err := client.CreateSessionTypedContext(ctx, &capture.CreateSessionOptions{CDP: true})
+	if err != nil {
+		t.Fatalf("CreateSessionTyped() error: %v", err)
+	}
+	if session.ConnectURL == "" {
+		t.Fatal("expected a connectUrl for CDP sessions")
+	}
+	if !server.ExpireSession(session.ID) {
+		t.Fatal("ExpireSession() = false")
+	}
+	if _, err := session.Goto(ctx, capture.GotoAction{URL: "https://example.com"}); !errors.Is(err, capture.ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionExpired, got %v", err)
+	}
+	if err := session.Refresh(ctx); err != nil || session.Status != "expired" {
+		t.Fatalf("Refresh() = %q, %v", session.Status, err)
+	}
+
+	closed, err := client.CreateSessionTypedContext(ctx, nil)
+	if err != nil {
+		t.Fatalf("CreateSessionTyped() error: %v", err)
+	}
+	if err := closed.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	_, err = client.ExecuteActionContext(ctx, closed.ID, capture.ActionGoto, capture.SessionActionPayload{"url": "https://example.com"})
+	if !errors.Is(err, capture.ErrSessionClosed) || errors.Is(err, capture.ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionClosed, got %v", err)
+	}
+
+	var statuses []int
+	for _, req := range server.Requests() {
+		statuses = append(statuses, req.StatusCode)
+	}
+	if statuses[0] != http.StatusNotFound || statuses[2] != http.StatusUnauthorized {
+		t.Fatalf("unexpected statuses: %v", statuses)
+	}
+}
+
+func TestServerHandleAction(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := server.Client()
+	ctx := context.Background()
+
+	server.HandleAction(capture.ActionEvaluate, func(sessionID string, payload capture.SessionActionPayload) (capture.SessionActionResponse, error) {
+		if payload["expression"] == "fail()" {
+			return nil, errors.New("ReferenceError: fail is not defined")
+		}
+		return capture.SessionActionResponse{"result": "Example Domain"}, nil
+	})
+
+	session, err := client.CreateSessionTypedContext(ctx, nil)
+	if err != nil {
+		t.Fatalf("CreateSessionTyped() error: %v", err)
+	}
+	result, err := session.Evaluate(ctx, "document.title")
+	if err != nil || result.Result != "Example Domain" || !result.Success {
+		t.Fatalf("Evaluate() = %+v, %v", result, err)
+	}
+	if _, err := session.Evaluate(ctx, "fail()"); err == nil || err.Error() != "ReferenceError: fail is not defined" {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package capture
+
+import (
+	"context"
+	"io"
+)
+
+// Client is the set of request methods implemented by *Capture. Accept it
+// instead of *Capture in code that should be testable with a fake, such as
+// capturetest.FakeClient.
+type Client interface {
+	BuildURL(requestType RequestType, targetURL string, options RequestOptions) (string, error)
+	BuildImageURL(targetURL string, options RequestOptions) (string, error)
+	BuildImageURLTyped(targetURL string, options *ScreenshotOptions) (string, error)
+	BuildPDFURL(targetURL string, options RequestOptions) (string, error)
+	BuildPDFURLTyped(targetURL string, options *PDFOptions) (string, error)
+	BuildContentURL(targetURL string, options RequestOptions) (string, error)
+	BuildMetadataURL(targetURL string, options RequestOptions) (string, error)
+	BuildAnimatedURL(targetURL string, options RequestOptions) (string, error)
+
+	FetchImage(targetURL string, options RequestOptions) ([]byte, error)
+	FetchImageContext(ctx context.Context, targetURL string, options RequestOptions) ([]byte, error)
+	FetchImageTyped(targetURL string, options *ScreenshotOptions) ([]byte, error)
+	FetchImageTypedContext(ctx context.Context, targetURL string, options *ScreenshotOptions) ([]byte, error)
+	FetchImageTo(w io.Writer, targetURL string, options RequestOptions) (int64, error)
+	FetchImageToContext(ctx context.Context, w io.Writer, targetURL string, options RequestOptions) (int64, error)
+
+	FetchPDF(targetURL string, options RequestOptions) ([]byte, error)
+	FetchPDFContext(ctx context.Context, targetURL string, options RequestOptions) ([]byte, error)
+	FetchPDFTyped(targetURL string, options *PDFOptions) ([]byte, error)
+	FetchPDFTypedContext(ctx context.Context, targetURL string, options *PDFOptions) ([]byte, error)
+	FetchPDFTo(w io.Writer, targetURL string, options RequestOptions) (int64, error)
+	FetchPDFToContext(ctx context.Context, w io.Writer, targetURL string, options RequestOptions) (int64, error)
+
+	FetchContent(targetURL string, options RequestOptions) (*ContentResponse, error)
+	FetchContentContext(ctx context.Context, targetURL string, options RequestOptions) (*ContentResponse, error)
+	FetchMetadata(targetURL string, options RequestOptions) (*MetadataResponse, error)
+	FetchMetadataContext(ctx context.Context, targetURL string, options RequestOptions) (*MetadataResponse, error)
+
+	FetchAnimated(targetURL string, options RequestOptions) ([]byte, error)
+	FetchAnimatedContext(ctx context.Context, targetURL string, options RequestOptions) ([]byte, error)
+	FetchAnimatedTo(w io.Writer, targetURL string, options RequestOptions) (int64, error)
+	FetchAnimatedToContext(ctx context.Context, w io.Writer, targetURL string, options RequestOptions) (int64, error)
+
+	BuildCreateSessionRequest(options *CreateSessionOptions) SessionRequestPreview
+	BuildGetSessionRequest(sessionID string) SessionRequestPreview
+	BuildCloseSessionRequest(sessionID string) SessionRequestPreview
+	BuildExecuteActionRequest(sessionID, actionType string, payload SessionActionPayload) SessionRequestPreview
+
+	CreateSession(options *CreateSessionOptions) (SessionResponse, error)
+	CreateSessionContext(ctx context.Context, options *CreateSessionOptions) (SessionResponse, error)
+	CreateSessionTyped(options *CreateSessionOptions) (*Session, error)
+	CreateSessionTypedContext(ctx context.Context, options *CreateSessionOptions) (*Session, error)
+
+	GetSession(sessionID string) (SessionResponse, error)
+	GetSessionContext(ctx context.Context, sessionID string) (SessionResponse, error)
+	GetSessionTyped(sessionID string) (*Session, error)
+	GetSessionTypedContext(ctx context.Context, sessionID string) (*Session, error)
+
+	CloseSession(sessionID string) (SessionResponse, error)
+	CloseSessionContext(ctx context.Context, sessionID string) (SessionResponse, error)
+	CloseSessionTyped(sessionID string) (*Session, error)
+	CloseSessionTypedContext(ctx context.Context, sessionID string) (*Session, error)
+
+	ExecuteAction(sessionID, actionType string, payload SessionActionPayload) (SessionActionResponse, error)
+	ExecuteActionContext(ctx context.Context, sessionID, actionType string, payload SessionActionPayload) (SessionActionResponse, error)
+	ExecuteActionTyped(sessionID, actionType string, payload SessionActionPayload) (*ActionResult, error)
+	ExecuteActionTypedContext(ctx context.Context, sessionID, actionType string, payload SessionActionPayload) (*ActionResult, error)
+
+	AttachSession(sessionID string) *Session
+}
+
+var _ Client = (*Capture)(nil)
+
+// Bind makes the session send its requests through client and returns it.
+// Client implementations use it to hand out working Session handles.
+func (s *Session) Bind(client Client) *Session {
+	s.client = client
+	return s
+}
@@ -0,0 +1,28 @@
+package capture
+
+import (
+	"context"
+	"testing"
+)
+
+type stubClient struct {
+	*Capture
+	actions []string
+}
+
+func (c *stubClient) ExecuteActionContext(ctx context.Context, sessionID, actionType string, payload SessionActionPayload) (SessionActionResponse, error) {
+	c.actions = append(c.actions, sessionID+":"+actionType)
+	return SessionActionResponse{"success": true}, nil
+}
+
+func TestSessionBind(t *testing.T) {
+	stub := &stubClient{Capture: New("key", "secret")}
+	session := New("key", "secret").AttachSession("sess_123").Bind(stub)
+
+	if _, err := session.Goto(context.Background(), GotoAction{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Goto() error: %v", err)
+	}
+	if len(stub.actions) != 1 || stub.actions[0] != "sess_123:goto" {
+		t.Fatalf("expected the action to go through the bound client, got %v", stub.actions)
+	}
+}
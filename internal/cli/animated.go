@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 )
@@ -56,10 +57,12 @@ func runAnimated(cmd *cobra.Command, args []string) error {
 
 	verboseLog("Creating animated capture of %s", targetURL)
 
-	data, err := client.FetchAnimated(targetURL, opts)
+	err = streamOutput(animatedOutput, func(w io.Writer) (int64, error) {
+		return client.FetchAnimatedToContext(cmd.Context(), w, targetURL, opts)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create animated capture: %w", err)
 	}
 
-	return writeOutput(data, animatedOutput)
+	return nil
 }
@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	capture "github.com/techulus/capture-go"
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch [file]",
+	Short: "Capture many URLs concurrently",
+	Long: `Run captures for a list of URLs concurrently.
+
+URLs are read from the given file, or from stdin when the file is omitted or
+"-". Each non-empty line is either a URL or a JSON object with per-URL
+settings; lines starting with # are ignored:
+
+  https://example.com
+  {"url": "https://example.org", "type": "pdf", "options": {"format": "A4"}}
+  {"url": "https://example.net", "output": "custom/name.png"}
+
+Options given with -X apply to every job; per-line options override them.
+
+Outputs are written using --output-template, a Go template with the fields
+.Index, .ID, .Type, .Host, .Slug and .Ext. Content and metadata jobs are
+written as JSON.
+
+Examples:
+  capture batch urls.txt
+  capture batch urls.txt --type pdf -X format=A4 -o 'pdfs/{{.Host}}.pdf'
+  cat urls.txt | capture batch --concurrency 8 -X vw=1280 -o 'shots/{{.Index}}-{{.Slug}}.{{.Ext}}'`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runBatch,
+}
+
+var (
+	batchType           string
+	batchConcurrency    int
+	batchOutputTemplate string
+	batchOptions        []string
+)
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().StringVar(&batchType, "type", "image", "Default request type: image, pdf, content, metadata, animated")
+	batchCmd.Flags().IntVarP(&batchConcurrency, "concurrency", "c", capture.DefaultBatchConcurrency, "Maximum number of concurrent requests")
+	batchCmd.Flags().StringVarP(&batchOutputTemplate, "output-template", "o", "{{.Index}}.{{.Ext}}", "Output file name template")
+	batchCmd.Flags().StringArrayVarP(&batchOptions, "option", "X", nil, "API option applied to every job as key=value (can be repeated)")
+}
+
+// batchLine is the JSON form of a line in a batch input file.
+type batchLine struct {
+	ID      string                 `json:"id"`
+	URL     string                 `json:"url"`
+	Type    string                 `json:"type"`
+	Options map[string]interface{} `json:"options"`
+	Output  string                 `json:"output"`
+}
+
+// batchEntry is a parsed job together with the file its output goes to.
+type batchEntry struct {
+	job    capture.BatchJob
+	output string
+}
+
+// batchOutputName holds the fields available to --output-template.
+type batchOutputName struct {
+	Index int
+	ID    string
+	Type  string
+	Host  string
+	Slug  string
+	Ext   string
+}
+
+var requestTypes = map[string]capture.RequestType{
+	"image":    capture.RequestTypeImage,
+	"pdf":      capture.RequestTypePDF,
+	"content":  capture.RequestTypeContent,
+	"metadata": capture.RequestTypeMetadata,
+	"animated": capture.RequestTypeAnimated,
+}
+
+func parseRequestType(name string) (capture.RequestType, error) {
+	requestType, ok := requestTypes[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("invalid type: %s (use image, pdf, content, metadata, or animated)", name)
+	}
+	return requestType, nil
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	defaultType, err := parseRequestType(batchType)
+	if err != nil {
+		return err
+	}
+
+	defaults, err := parseOptions(batchOptions)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(batchOutputTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid --output-template: %w", err)
+	}
+
+	input := io.Reader(os.Stdin)
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", args[0], err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	entries, err := parseBatchInput(input, defaultType, defaults, tmpl)
+	if err != nil {
+		return err
+	}
+
+	client := newCaptureClient()
+
+	if dryRun {
+		for _, entry := range entries {
+			url, err := client.BuildURL(entry.job.Type, entry.job.URL, entry.job.Options)
+			if err != nil {
+				return err
+			}
+			fmt.Println(url)
+		}
+		return nil
+	}
+
+	jobs := make([]capture.BatchJob, len(entries))
+	for i, entry := range entries {
+		jobs[i] = entry.job
+	}
+
+	verboseLog("Running %d jobs with concurrency %d", len(jobs), batchConcurrency)
+
+	var failures []capture.BatchResult
+	for result := range client.Batch(cmd.Context(), jobs, batchConcurrency) {
+		if result.Err == nil {
+			result.Err = writeBatchResult(result, entries[result.Index].output)
+		}
+		if result.Err != nil {
+			failures = append(failures, result)
+			verboseLog("[%d] %s failed: %v", result.Index, result.Job.URL, result.Err)
+			continue
+		}
+		verboseLog("[%d] %s done in %s", result.Index, result.Job.URL, result.Duration.Round(time.Millisecond))
+	}
+
+	fmt.Fprintf(os.Stderr, "Completed %d jobs: %d succeeded, %d failed\n", len(jobs), len(jobs)-len(failures), len(failures))
+	if len(failures) == 0 {
+		return nil
+	}
+
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "  [%d] %s: %v\n", failure.Index, failure.Job.URL, failure.Err)
+	}
+	return fmt.Errorf("%d of %d jobs failed", len(failures), len(jobs))
+}
+
+func parseBatchInput(r io.Reader, defaultType capture.RequestType, defaults capture.RequestOptions, tmpl *template.Template) ([]batchEntry, error) {
+	var entries []batchEntry
+	outputs := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		spec := batchLine{URL: line}
+		if strings.HasPrefix(line, "{") {
+			spec = batchLine{}
+			if err := json.Unmarshal([]byte(line), &spec); err != nil {
+				return nil, fmt.Errorf("line %d: invalid JSON: %w", lineNumber, err)
+			}
+			if spec.URL == "" {
+				return nil, fmt.Errorf("line %d: url is required", lineNumber)
+			}
+		}
+
+		requestType := defaultType
+		if spec.Type != "" {
+			parsed, err := parseRequestType(spec.Type)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			requestType = parsed
+		}
+
+		options := capture.RequestOptions{}
+		for key, value := range defaults {
+			options[key] = value
+		}
+		for key, value := range spec.Options {
+			options[key] = value
+		}
+
+		index := len(entries)
+		id := spec.ID
+		if id == "" {
+			id = strconv.Itoa(index)
+		}
+		job := capture.BatchJob{ID: id, Type: requestType, URL: spec.URL, Options: options}
+
+		output := spec.Output
+		if output == "" {
+			name, err := batchOutputPath(tmpl, index, job)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			output = name
+		}
+		if previous, ok := outputs[output]; ok {
+			return nil, fmt.Errorf("line %d: output %s is also used by job %d", lineNumber, output, previous)
+		}
+		outputs[output] = index
+
+		entries = append(entries, batchEntry{job: job, output: output})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch input: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no URLs found in batch input")
+	}
+
+	return entries, nil
+}
+
+var slugPattern = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+func batchOutputPath(tmpl *template.Template, index int, job capture.BatchJob) (string, error) {
+	host := ""
+	if parsed, err := url.Parse(job.URL); err == nil {
+		host = parsed.Hostname()
+	}
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.TrimPrefix(strings.TrimPrefix(job.URL, "https://"), "http://"), "-"), "-")
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, batchOutputName{
+		Index: index,
+		ID:    job.ID,
+		Type:  string(job.Type),
+		Host:  host,
+		Slug:  slug,
+		Ext:   batchExtension(job),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render output template: %w", err)
+	}
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("output template rendered an empty file name")
+	}
+	return buf.String(), nil
+}
+
+func batchExtension(job capture.BatchJob) string {
+	switch job.Type {
+	case capture.RequestTypeImage:
+		if format, ok := job.Options["type"].(string); ok && format != "" {
+			return format
+		}
+		return "png"
+	case capture.RequestTypePDF:
+		return "pdf"
+	case capture.RequestTypeAnimated:
+		if format, ok := job.Options["format"].(string); ok && format != "" {
+			return format
+		}
+		return "gif"
+	default:
+		return "json"
+	}
+}
+
+func writeBatchResult(result capture.BatchResult, output string) error {
+	data := result.Data
+	var value interface{}
+	switch {
+	case result.Content != nil:
+		value = result.Content
+	case result.Metadata != nil:
+		value = result.Metadata
+	}
+	if value != nil {
+		encoded, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		data = encoded
+	}
+
+	if dir := filepath.Dir(output); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	return writeOutput(data, output)
+}
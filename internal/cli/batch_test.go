@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func TestParseBatchInput(t *testing.T) {
+	input := strings.NewReader(`
+# comment
+https://example.com
+{"url": "https://example.org/docs", "type": "pdf", "options": {"format": "Letter"}}
+{"id": "home", "url": "https://example.net", "output": "custom.png", "options": {"vw": 800}}
+`)
+	tmpl := template.Must(template.New("output").Parse("out/{{.Index}}-{{.Host}}.{{.Ext}}"))
+
+	entries, err := parseBatchInput(input, capture.RequestTypeImage, capture.RequestOptions{"vw": 1280, "format": "A4"}, tmpl)
+	if err != nil {
+		t.Fatalf("parseBatchInput() unexpected error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+
+	if entries[0].job.Type != capture.RequestTypeImage || entries[0].output != "out/0-example.com.png" {
+		t.Errorf("unexpected first entry: %#v", entries[0])
+	}
+	if entries[1].job.Type != capture.RequestTypePDF || entries[1].output != "out/1-example.org.pdf" {
+		t.Errorf("unexpected second entry: %#v", entries[1])
+	}
+	if entries[1].job.Options["format"] != "Letter" || entries[1].job.Options["vw"] != 1280 {
+		t.Errorf("expected per-line options to override defaults, got %#v", entries[1].job.Options)
+	}
+	if entries[2].job.ID != "home" || entries[2].output != "custom.png" || entries[2].job.Options["vw"] != float64(800) {
+		t.Errorf("unexpected third entry: %#v", entries[2])
+	}
+}
+
+func TestParseBatchInputRejectsDuplicateOutputs(t *testing.T) {
+	input := strings.NewReader("https://example.com/a\nhttps://example.com/b\n")
+	tmpl := template.Must(template.New("output").Parse("{{.Host}}.{{.Ext}}"))
+
+	if _, err := parseBatchInput(input, capture.RequestTypeImage, nil, tmpl); err == nil {
+		t.Fatal("expected duplicate output error")
+	}
+}
+
+func TestParseBatchInputErrors(t *testing.T) {
+	tmpl := template.Must(template.New("output").Parse("{{.Index}}.{{.Ext}}"))
+
+	for _, input := range []string{"", "# only comments\n", `{"type": "pdf"}`, `{"url": "https://example.com", "type": "video"}`} {
+		if _, err := parseBatchInput(strings.NewReader(input), capture.RequestTypeImage, nil, tmpl); err == nil {
+			t.Errorf("expected error for input %q", input)
+		}
+	}
+}
+
+func TestBatchExtension(t *testing.T) {
+	tests := []struct {
+		job  capture.BatchJob
+		want string
+	}{
+		{capture.BatchJob{Type: capture.RequestTypeImage}, "png"},
+		{capture.BatchJob{Type: capture.RequestTypeImage, Options: capture.RequestOptions{"type": "webp"}}, "webp"},
+		{capture.BatchJob{Type: capture.RequestTypePDF}, "pdf"},
+		{capture.BatchJob{Type: capture.RequestTypeAnimated, Options: capture.RequestOptions{"format": "mp4"}}, "mp4"},
+		{capture.BatchJob{Type: capture.RequestTypeContent}, "json"},
+	}
+
+	for _, tt := range tests {
+		if got := batchExtension(tt.job); got != tt.want {
+			t.Errorf("batchExtension(%#v) = %s, want %s", tt.job, got, tt.want)
+		}
+	}
+}
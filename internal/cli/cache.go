@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	capture "github.com/techulus/capture-go"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and prune the local response cache",
+	Long: `Inspect and prune the on-disk response cache used by --cache-dir.
+
+Pass the same --cache-dir that the fetch commands use. These commands do not
+need CAPTURE_KEY or CAPTURE_SECRET.
+
+Examples:
+  capture screenshot https://example.com --cache-dir ~/.cache/capture -o shot.png
+  capture cache list --cache-dir ~/.cache/capture
+  capture cache prune --cache-dir ~/.cache/capture --cache-ttl 1h
+  capture cache clear --cache-dir ~/.cache/capture`,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cached responses",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheList,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove expired entries and enforce --cache-max-bytes",
+	Args:  cobra.NoArgs,
+	RunE:  runCachePrune,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached response",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheClear,
+}
+
+var cacheJSON bool
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheListCmd, cachePruneCmd, cacheClearCmd)
+
+	cacheListCmd.Flags().BoolVar(&cacheJSON, "json", false, "Output entries as JSON")
+}
+
+func isCacheCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == cacheCmd {
+			return true
+		}
+	}
+	return false
+}
+
+// openCache opens the cache named by --cache-dir. There is no default
+// directory, since fetch commands only cache when --cache-dir is given.
+func openCache() (*capture.DiskCache, error) {
+	if cacheDir == "" {
+		return nil, fmt.Errorf("--cache-dir is required")
+	}
+
+	cache := capture.NewDiskCache(cacheDir, cacheTTL)
+	cache.MaxBytes = cacheMaxBytes
+	return cache, nil
+}
+
+func runCacheList(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	entries, err := cache.Entries()
+	if err != nil {
+		return err
+	}
+
+	if cacheJSON {
+		if entries == nil {
+			entries = []capture.CacheEntry{}
+		}
+		return emitJSON(entries, true)
+	}
+
+	var total int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tSIZE\tAGE\tSTATUS\tURL")
+	for _, entry := range entries {
+		status := "fresh"
+		if entry.Expired {
+			status = "expired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", entry.Key[:12], entry.RequestType, entry.Size, time.Since(entry.CreatedAt).Round(time.Second), status, entry.URL)
+		total += entry.Size
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%d entries, %d bytes in %s\n", len(entries), total, cache.Dir)
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	removed, freed, err := cache.Prune()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Removed %d entries (%d bytes) from %s\n", removed, freed, cache.Dir)
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	if err := cache.Clear(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Cleared %s\n", cache.Dir)
+	return nil
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCacheCommandsSkipCredentials(t *testing.T) {
+	t.Setenv("CAPTURE_KEY", "")
+	t.Setenv("CAPTURE_SECRET", "")
+
+	for _, cmd := range []*cobra.Command{cacheListCmd, cachePruneCmd, cacheClearCmd} {
+		if err := rootCmd.PersistentPreRunE(cmd, nil); err != nil {
+			t.Fatalf("expected cache %s to skip credential check, got %v", cmd.Name(), err)
+		}
+	}
+}
+
+func TestOpenCacheUsesFlags(t *testing.T) {
+	prevDir, prevMax := cacheDir, cacheMaxBytes
+	defer func() { cacheDir, cacheMaxBytes = prevDir, prevMax }()
+
+	cacheDir = t.TempDir()
+	cacheMaxBytes = 1234
+
+	cache, err := openCache()
+	if err != nil {
+		t.Fatalf("openCache() unexpected error: %v", err)
+	}
+	if cache.Dir != cacheDir || cache.MaxBytes != 1234 {
+		t.Fatalf("unexpected cache: %#v", cache)
+	}
+}
+
+func TestOpenCacheRequiresDir(t *testing.T) {
+	prevDir := cacheDir
+	defer func() { cacheDir = prevDir }()
+
+	cacheDir = ""
+	if _, err := openCache(); err == nil {
+		t.Fatal("expected openCache() to require --cache-dir")
+	}
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"net/http"
+
+	"github.com/techulus/capture-go/capturetest"
+)
+
+var (
+	cassetteRecordPath string
+	cassetteReplayPath string
+
+	// cliCassette records or replays the command's API traffic when
+	// --record or --replay is set.
+	cliCassette *capturetest.Cassette
+)
+
+// replayCredential stands in for CAPTURE_KEY and CAPTURE_SECRET when
+// replaying, since cassettes match requests regardless of credentials.
+const replayCredential = "replay"
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cassetteRecordPath, "record", "", "Record API requests and responses to this cassette file")
+	rootCmd.PersistentFlags().StringVar(&cassetteReplayPath, "replay", "", "Answer API requests from this cassette file instead of the network")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+}
+
+// openCassette prepares the cassette selected by --record or --replay.
+func openCassette() error {
+	var err error
+	switch {
+	case cassetteRecordPath != "":
+		cliCassette, err = capturetest.NewCassette(cassetteRecordPath, capturetest.CassetteRecord)
+	case cassetteReplayPath != "":
+		cliCassette, err = capturetest.NewCassette(cassetteReplayPath, capturetest.CassetteReplay)
+		if err == nil {
+			verboseLog("Replaying %d recorded requests from %s", len(cliCassette.Interactions()), cassetteReplayPath)
+		}
+	}
+	return err
+}
+
+// cassetteTransport wraps transport with the cassette, if any.
+func cassetteTransport(transport http.RoundTripper) http.RoundTripper {
+	if cliCassette == nil {
+		return transport
+	}
+	cliCassette.Transport = transport
+	return cliCassette
+}
+
+// saveCassette writes a recording cassette after the command finishes,
+// including when it fails, so failing runs can be replayed.
+func saveCassette() error {
+	if cliCassette == nil || cliCassette.Mode != capturetest.CassetteRecord {
+		return nil
+	}
+	if err := cliCassette.Save(); err != nil {
+		return err
+	}
+	verboseLog("Recorded %d requests to %s", len(cliCassette.Interactions()), cliCassette.Path)
+	return nil
+}
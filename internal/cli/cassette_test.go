@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/techulus/capture-go/capturetest"
+)
+
+func TestCassetteRecordAndReplayFlags(t *testing.T) {
+	prevRecord, prevReplay, prevCassette := cassetteRecordPath, cassetteReplayPath, cliCassette
+	prevKey, prevSecret := captureKey, captureSecret
+	defer func() {
+		cassetteRecordPath, cassetteReplayPath, cliCassette = prevRecord, prevReplay, prevCassette
+		captureKey, captureSecret = prevKey, prevSecret
+	}()
+
+	server := capturetest.NewServer()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	t.Setenv("CAPTURE_KEY", capturetest.DefaultKey)
+	t.Setenv("CAPTURE_SECRET", capturetest.DefaultSecret)
+	t.Setenv("CAPTURE_API_URL", server.URL)
+	t.Setenv("CAPTURE_SESSIONS_URL", server.URL)
+
+	cassetteRecordPath, cassetteReplayPath = path, ""
+	if err := rootCmd.PersistentPreRunE(screenshotCmd, nil); err != nil {
+		t.Fatalf("PersistentPreRunE() error: %v", err)
+	}
+	recorded, err := newCaptureClient().FetchContent("https://example.com", nil)
+	if err != nil {
+		t.Fatalf("FetchContent() while recording: %v", err)
+	}
+	if err := saveCassette(); err != nil {
+		t.Fatalf("saveCassette() error: %v", err)
+	}
+	server.Close()
+
+	t.Setenv("CAPTURE_KEY", "")
+	t.Setenv("CAPTURE_SECRET", "")
+	cassetteRecordPath, cassetteReplayPath = "", path
+	if err := rootCmd.PersistentPreRunE(screenshotCmd, nil); err != nil {
+		t.Fatalf("expected replay to run without credentials, got %v", err)
+	}
+	if captureKey != replayCredential || captureSecret != replayCredential {
+		t.Fatalf("unexpected replay credentials %q, %q", captureKey, captureSecret)
+	}
+
+	client := newCaptureClient()
+	replayed, err := client.FetchContent("https://example.com", nil)
+	if err != nil || replayed.Markdown != recorded.Markdown {
+		t.Fatalf("replayed FetchContent() = %+v, %v", replayed, err)
+	}
+	if _, err := client.FetchMetadata("https://example.com", nil); !errors.Is(err, capturetest.ErrNotRecorded) {
+		t.Fatalf("expected ErrNotRecorded, got %v", err)
+	}
+	if err := saveCassette(); err != nil {
+		t.Fatalf("saveCassette() while replaying: %v", err)
+	}
+}
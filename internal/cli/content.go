@@ -67,7 +67,7 @@ func runContent(cmd *cobra.Command, args []string) error {
 
 	verboseLog("Extracting content from %s", targetURL)
 
-	content, err := client.FetchContent(targetURL, opts)
+	content, err := client.FetchContentContext(cmd.Context(), targetURL, opts)
 	if err != nil {
 		return fmt.Errorf("failed to extract content: %w", err)
 	}
@@ -59,7 +59,7 @@ func runMetadata(cmd *cobra.Command, args []string) error {
 
 	verboseLog("Extracting metadata from %s", targetURL)
 
-	metadata, err := client.FetchMetadata(targetURL, opts)
+	metadata, err := client.FetchMetadataContext(cmd.Context(), targetURL, opts)
 	if err != nil {
 		return fmt.Errorf("failed to extract metadata: %w", err)
 	}
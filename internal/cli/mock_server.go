@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/techulus/capture-go/capturetest"
+)
+
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Run a local fake of the Capture API for offline development",
+	Long: `Start a local HTTP server implementing the render routes
+(/<key>/<token>/<type>) and the /v1/sessions API with placeholder outputs:
+generated PNG/JPEG images sized by vw and vh, a one-page PDF, a short GIF,
+and canned content and metadata. Sessions and their actions are simulated in
+memory.
+
+Render tokens and session bearer tokens are verified against --key and
+--secret, which default to CAPTURE_KEY and CAPTURE_SECRET when set, or to
+test_key and test_secret. No real credentials or network access are needed.
+
+Point the CLI at the server with CAPTURE_API_URL and CAPTURE_SESSIONS_URL,
+or set APIURL, EdgeURL and SessionsURL on a capture.Capture.
+
+Examples:
+  capture mock-server
+  capture mock-server --listen 127.0.0.1:9000 --key my_key --secret my_secret
+
+  # in another shell
+  export CAPTURE_KEY=test_key CAPTURE_SECRET=test_secret
+  export CAPTURE_API_URL=http://127.0.0.1:8787 CAPTURE_SESSIONS_URL=http://127.0.0.1:8787
+  capture screenshot https://example.com -o placeholder.png`,
+	Args: cobra.NoArgs,
+	RunE: runMockServer,
+}
+
+var (
+	mockServerListen string
+	mockServerKey    string
+	mockServerSecret string
+)
+
+func init() {
+	rootCmd.AddCommand(mockServerCmd)
+
+	mockServerCmd.Flags().StringVar(&mockServerListen, "listen", "127.0.0.1:8787", "Address to serve the fake API on")
+	mockServerCmd.Flags().StringVar(&mockServerKey, "key", "", "API key requests must be signed with (default: CAPTURE_KEY or test_key)")
+	mockServerCmd.Flags().StringVar(&mockServerSecret, "secret", "", "API secret requests must be signed with (default: CAPTURE_SECRET or test_secret)")
+}
+
+// mockServerCredentials picks the flag, then the environment, then the
+// capturetest defaults.
+func mockServerCredentials() (string, string) {
+	key, secret := mockServerKey, mockServerSecret
+	if key == "" {
+		key = os.Getenv("CAPTURE_KEY")
+	}
+	if secret == "" {
+		secret = os.Getenv("CAPTURE_SECRET")
+	}
+	if key == "" {
+		key = capturetest.DefaultKey
+	}
+	if secret == "" {
+		secret = capturetest.DefaultSecret
+	}
+	return key, secret
+}
+
+func runMockServer(cmd *cobra.Command, args []string) error {
+	key, secret := mockServerCredentials()
+	api := capturetest.NewAPI(capturetest.WithCredentials(key, secret))
+
+	listener, err := net.Listen("tcp", mockServerListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", mockServerListen, err)
+	}
+	defer listener.Close()
+
+	server := &http.Server{Handler: logRequests(api)}
+	ctx := cmd.Context()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	baseURL := "http://" + listener.Addr().String()
+	fmt.Fprintf(os.Stderr, "Mock Capture API listening on %s\n\n", baseURL)
+	fmt.Fprintf(os.Stderr, "  export CAPTURE_KEY=%s CAPTURE_SECRET=%s\n", key, secret)
+	fmt.Fprintf(os.Stderr, "  export CAPTURE_API_URL=%s CAPTURE_SESSIONS_URL=%s\n\n", baseURL, baseURL)
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("mock server failed: %w", err)
+	}
+	return nil
+}
+
+// logRequests prints one line per request to stderr.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &loggedResponse{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		fmt.Fprintf(os.Stderr, "%s %s %d %s\n", r.Method, r.URL.RequestURI(), rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+type loggedResponse struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *loggedResponse) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
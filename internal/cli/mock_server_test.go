@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"image/png"
+	"net/http/httptest"
+	"testing"
+
+	capture "github.com/techulus/capture-go"
+	"github.com/techulus/capture-go/capturetest"
+)
+
+func TestMockServerCredentials(t *testing.T) {
+	prevKey, prevSecret := mockServerKey, mockServerSecret
+	defer func() { mockServerKey, mockServerSecret = prevKey, prevSecret }()
+
+	t.Setenv("CAPTURE_KEY", "")
+	t.Setenv("CAPTURE_SECRET", "")
+	mockServerKey, mockServerSecret = "", ""
+	if key, secret := mockServerCredentials(); key != capturetest.DefaultKey || secret != capturetest.DefaultSecret {
+		t.Fatalf("default credentials = %q, %q", key, secret)
+	}
+
+	t.Setenv("CAPTURE_KEY", "env_key")
+	t.Setenv("CAPTURE_SECRET", "env_secret")
+	if key, secret := mockServerCredentials(); key != "env_key" || secret != "env_secret" {
+		t.Fatalf("environment credentials = %q, %q", key, secret)
+	}
+
+	mockServerKey = "flag_key"
+	if key, secret := mockServerCredentials(); key != "flag_key" || secret != "env_secret" {
+		t.Fatalf("flag credentials = %q, %q", key, secret)
+	}
+}
+
+func TestMockServerServesCLIClient(t *testing.T) {
+	server := httptest.NewServer(logRequests(capturetest.NewAPI()))
+	defer server.Close()
+
+	prevKey, prevSecret := captureKey, captureSecret
+	defer func() { captureKey, captureSecret = prevKey, prevSecret }()
+	captureKey, captureSecret = capturetest.DefaultKey, capturetest.DefaultSecret
+	t.Setenv("CAPTURE_API_URL", server.URL)
+	t.Setenv("CAPTURE_SESSIONS_URL", server.URL)
+
+	client := newCaptureClient()
+	if client.APIURL != server.URL || client.EdgeURL != server.URL || client.SessionsURL != server.URL {
+		t.Fatalf("base URLs not overridden: %s %s %s", client.APIURL, client.EdgeURL, client.SessionsURL)
+	}
+
+	image, err := client.FetchImage("https://example.com", capture.RequestOptions{"vw": 640, "vh": 480})
+	if err != nil {
+		t.Fatalf("FetchImage() error: %v", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(image))
+	if err != nil || decoded.Bounds().Dx() != 640 || decoded.Bounds().Dy() != 480 {
+		t.Fatalf("unexpected placeholder image: %v, %v", decoded.Bounds(), err)
+	}
+
+	session, err := client.CreateSessionTyped(nil)
+	if err != nil || session.Status != "active" {
+		t.Fatalf("CreateSessionTyped() = %+v, %v", session, err)
+	}
+}
+
+func TestMockServerSkipsCredentialCheck(t *testing.T) {
+	t.Setenv("CAPTURE_KEY", "")
+	t.Setenv("CAPTURE_SECRET", "")
+	if err := rootCmd.PersistentPreRunE(mockServerCmd, nil); err != nil {
+		t.Fatalf("expected mock-server to run without credentials, got %v", err)
+	}
+}
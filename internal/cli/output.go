@@ -2,7 +2,9 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -28,6 +30,43 @@ func writeOutput(data []byte, outputFile string) error {
 	return nil
 }
 
+// streamOutput runs fetch against stdout or outputFile so large renders are
+// never held in memory. Files are written to a temporary sibling and renamed
+// into place once complete, so failed downloads do not leave partial output.
+func streamOutput(outputFile string, fetch func(io.Writer) (int64, error)) error {
+	if outputFile == "" || outputFile == "-" {
+		_, err := fetch(os.Stdout)
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(outputFile), "."+filepath.Base(outputFile)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write to %s: %w", outputFile, err)
+	}
+	tmpName := tmp.Name()
+
+	n, err := fetch(tmp)
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write to %s: %w", outputFile, closeErr)
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, outputFile)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Written to %s (%d bytes)\n", outputFile, n)
+	}
+
+	return nil
+}
+
 func writeStringOutput(data string, outputFile string) error {
 	return writeOutput([]byte(data), outputFile)
 }
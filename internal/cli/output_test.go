@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -121,3 +125,44 @@ func TestParseActionPayloadRejectsInvalidJSON(t *testing.T) {
 		t.Fatal("expected invalid JSON object error")
 	}
 }
+
+func TestStreamOutputWritesFile(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "out.png")
+
+	err := streamOutput(outputFile, func(w io.Writer) (int64, error) {
+		n, err := io.WriteString(w, "image-bytes")
+		return int64(n), err
+	})
+	if err != nil {
+		t.Fatalf("streamOutput() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if string(data) != "image-bytes" {
+		t.Fatalf("output = %q", data)
+	}
+}
+
+func TestStreamOutputLeavesNoPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "out.png")
+
+	err := streamOutput(outputFile, func(w io.Writer) (int64, error) {
+		n, _ := io.WriteString(w, "partial")
+		return int64(n), errors.New("connection reset")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no files after failed stream, found %d", len(entries))
+	}
+}
@@ -2,8 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
+	capture "github.com/techulus/capture-go"
 )
 
 var pdfCmd = &cobra.Command{
@@ -11,20 +13,36 @@ var pdfCmd = &cobra.Command{
 	Short: "Generate a PDF from a web page",
 	Long: `Generate a PDF document from the specified URL.
 
-Options are passed as key=value pairs using the -X flag.
+Common PDF options have dedicated flags. Any other option can be passed as a
+key=value pair using the -X flag; -X values override the dedicated flags.
 See https://docs.capture.page/ for available options.
 
+Paper formats: Letter, Legal, Tabloid, Ledger, A0, A1, A2, A3, A4, A5, A6
+
 Examples:
   capture pdf https://example.com -o document.pdf
-  capture pdf https://example.com -X format=A4 -X landscape=true -o landscape.pdf
-  capture pdf https://example.com -X printBackground=true -o styled.pdf`,
+  capture pdf https://example.com --format A4 --landscape -o landscape.pdf
+  capture pdf https://example.com --margin 1in --print-background -o styled.pdf
+  capture pdf https://example.com --width 8.5in --height 11in --page-ranges 1-2 -o custom.pdf`,
 	Args: cobra.ExactArgs(1),
 	RunE: runPDF,
 }
 
 var (
-	pdfOutput  string
-	pdfOptions []string
+	pdfOutput          string
+	pdfOptions         []string
+	pdfFormat          string
+	pdfWidth           string
+	pdfHeight          string
+	pdfMargin          string
+	pdfMarginTop       string
+	pdfMarginRight     string
+	pdfMarginBottom    string
+	pdfMarginLeft      string
+	pdfLandscape       bool
+	pdfScale           float64
+	pdfPageRanges      string
+	pdfPrintBackground bool
 )
 
 func init() {
@@ -32,12 +50,69 @@ func init() {
 
 	pdfCmd.Flags().StringVarP(&pdfOutput, "output", "o", "", "Output file (default: stdout)")
 	pdfCmd.Flags().StringArrayVarP(&pdfOptions, "option", "X", nil, "API option as key=value (can be repeated)")
+	pdfCmd.Flags().StringVar(&pdfFormat, "format", "", "Paper format (e.g. A4, Letter)")
+	pdfCmd.Flags().StringVar(&pdfWidth, "width", "", "Custom paper width with units (e.g. 8.5in)")
+	pdfCmd.Flags().StringVar(&pdfHeight, "height", "", "Custom paper height with units (e.g. 11in)")
+	pdfCmd.Flags().StringVar(&pdfMargin, "margin", "", "Margin for all sides with units (e.g. 1in)")
+	pdfCmd.Flags().StringVar(&pdfMarginTop, "margin-top", "", "Top margin with units")
+	pdfCmd.Flags().StringVar(&pdfMarginRight, "margin-right", "", "Right margin with units")
+	pdfCmd.Flags().StringVar(&pdfMarginBottom, "margin-bottom", "", "Bottom margin with units")
+	pdfCmd.Flags().StringVar(&pdfMarginLeft, "margin-left", "", "Left margin with units")
+	pdfCmd.Flags().BoolVar(&pdfLandscape, "landscape", false, "Use landscape orientation")
+	pdfCmd.Flags().Float64Var(&pdfScale, "scale", 0, "Rendering scale (0.1 to 2)")
+	pdfCmd.Flags().StringVar(&pdfPageRanges, "page-ranges", "", "Pages to print (e.g. 1-5, 8)")
+	pdfCmd.Flags().BoolVar(&pdfPrintBackground, "print-background", false, "Print background graphics")
+}
+
+// pdfRequestOptions combines the dedicated PDF flags with -X options. Explicit
+// -X values win so existing invocations keep their meaning.
+func pdfRequestOptions() (capture.RequestOptions, error) {
+	pdf := &capture.PDFOptions{
+		Width:           pdfWidth,
+		Height:          pdfHeight,
+		Margins:         capture.UniformPDFMargins(pdfMargin),
+		Landscape:       pdfLandscape,
+		Scale:           pdfScale,
+		PageRanges:      pdfPageRanges,
+		PrintBackground: pdfPrintBackground,
+	}
+	if pdfFormat != "" {
+		format, err := capture.ParsePaperFormat(pdfFormat)
+		if err != nil {
+			return nil, err
+		}
+		pdf.Format = format
+	}
+	for _, side := range []struct {
+		value  string
+		target *string
+	}{
+		{pdfMarginTop, &pdf.Margins.Top},
+		{pdfMarginRight, &pdf.Margins.Right},
+		{pdfMarginBottom, &pdf.Margins.Bottom},
+		{pdfMarginLeft, &pdf.Margins.Left},
+	} {
+		if side.value != "" {
+			*side.target = side.value
+		}
+	}
+
+	extra, err := parseOptions(pdfOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := pdf.RequestOptions()
+	for key, value := range extra {
+		opts[key] = value
+	}
+	return opts, nil
 }
 
 func runPDF(cmd *cobra.Command, args []string) error {
 	targetURL := args[0]
 
-	opts, err := parseOptions(pdfOptions)
+	opts, err := pdfRequestOptions()
 	if err != nil {
 		return err
 	}
@@ -55,10 +130,12 @@ func runPDF(cmd *cobra.Command, args []string) error {
 
 	verboseLog("Generating PDF from %s", targetURL)
 
-	data, err := client.FetchPDF(targetURL, opts)
+	err = streamOutput(pdfOutput, func(w io.Writer) (int64, error) {
+		return client.FetchPDFToContext(cmd.Context(), w, targetURL, opts)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to generate PDF: %w", err)
 	}
 
-	return writeOutput(data, pdfOutput)
+	return nil
 }
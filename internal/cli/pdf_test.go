@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"testing"
+)
+
+func TestPDFRequestOptions(t *testing.T) {
+	prevFormat, prevMargin, prevMarginTop, prevLandscape, prevOptions := pdfFormat, pdfMargin, pdfMarginTop, pdfLandscape, pdfOptions
+	defer func() {
+		pdfFormat, pdfMargin, pdfMarginTop, pdfLandscape, pdfOptions = prevFormat, prevMargin, prevMarginTop, prevLandscape, prevOptions
+	}()
+
+	pdfFormat = "letter"
+	pdfMargin = "1in"
+	pdfMarginTop = "2in"
+	pdfLandscape = true
+	pdfOptions = []string{"landscape=false", "scale=0.5"}
+
+	opts, err := pdfRequestOptions()
+	if err != nil {
+		t.Fatalf("pdfRequestOptions() unexpected error: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"format":       "Letter",
+		"marginTop":    "2in",
+		"marginRight":  "1in",
+		"marginBottom": "1in",
+		"marginLeft":   "1in",
+		"landscape":    false,
+		"scale":        0.5,
+	}
+	if len(opts) != len(want) {
+		t.Fatalf("pdfRequestOptions() = %#v, want %#v", opts, want)
+	}
+	for key, value := range want {
+		if opts[key] != value {
+			t.Errorf("pdfRequestOptions()[%s] = %v (%T), want %v (%T)", key, opts[key], opts[key], value, value)
+		}
+	}
+}
+
+func TestPDFRequestOptionsRejectsUnknownFormat(t *testing.T) {
+	prev := pdfFormat
+	defer func() { pdfFormat = prev }()
+
+	pdfFormat = "B7"
+	if _, err := pdfRequestOptions(); err == nil {
+		t.Fatal("expected unknown paper format error")
+	}
+}
@@ -1,9 +1,13 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	capture "github.com/techulus/capture-go"
@@ -17,6 +21,13 @@ var (
 	timeout time.Duration
 	dryRun  bool
 
+	retries      int
+	retryMaxWait time.Duration
+
+	cacheDir      string
+	cacheTTL      time.Duration
+	cacheMaxBytes int64
+
 	captureKey    string
 	captureSecret string
 )
@@ -31,14 +42,31 @@ Documentation: https://docs.capture.page/
 
 Authentication is done via environment variables:
   CAPTURE_KEY    - Your Capture API key
-  CAPTURE_SECRET - Your Capture API secret`,
+  CAPTURE_SECRET - Your Capture API secret
+
+CAPTURE_API_URL and CAPTURE_SESSIONS_URL override the render and Sessions
+API base URLs, e.g. to use "capture mock-server".`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		if cmd.Name() == "version" || cmd.Name() == "completion" || cmd.Name() == "help" {
+		if cmd.Name() == "version" || cmd.Name() == "completion" || cmd.Name() == "help" || isCacheCommand(cmd) || cmd == mockServerCmd {
 			return nil
 		}
 
+		if err := openCassette(); err != nil {
+			return err
+		}
+
 		captureKey = os.Getenv("CAPTURE_KEY")
 		captureSecret = os.Getenv("CAPTURE_SECRET")
+		if cassetteReplayPath != "" {
+			// Replayed requests match regardless of credentials, so CI can
+			// run without them.
+			if captureKey == "" {
+				captureKey = replayCredential
+			}
+			if captureSecret == "" {
+				captureSecret = replayCredential
+			}
+		}
 
 		if captureKey == "" || captureSecret == "" {
 			// Session dry-run previews intentionally omit credentials, so they
@@ -54,8 +82,56 @@ Authentication is done via environment variables:
 	},
 }
 
+// Execute runs the root command. Interrupt and termination signals cancel
+// the command context so in-flight API requests are aborted.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
+	for _, finish := range []func() error{writeSessionsTrace, saveCassette} {
+		if finishErr := finish(); finishErr != nil {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", finishErr)
+			} else {
+				err = finishErr
+			}
+		}
+	}
+	return err
+}
+
+// Exit codes returned by the CLI for classified API failures. Any other
+// error exits with ExitError.
+const (
+	ExitError           = 1
+	ExitUnauthorized    = 3
+	ExitRateLimited     = 4
+	ExitSessionNotFound = 5
+	ExitSessionExpired  = 6
+	ExitInvalidAction   = 7
+	ExitSessionClosed   = 8
+)
+
+// ExitCode maps an error returned by Execute to the process exit code.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, capture.ErrUnauthorized):
+		return ExitUnauthorized
+	case errors.Is(err, capture.ErrRateLimited):
+		return ExitRateLimited
+	case errors.Is(err, capture.ErrSessionNotFound):
+		return ExitSessionNotFound
+	case errors.Is(err, capture.ErrSessionExpired):
+		return ExitSessionExpired
+	case errors.Is(err, capture.ErrSessionClosed):
+		return ExitSessionClosed
+	case errors.Is(err, capture.ErrInvalidAction):
+		return ExitInvalidAction
+	}
+	return ExitError
 }
 
 func init() {
@@ -63,11 +139,17 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Second, "Request timeout")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the request URL without executing")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 0, "Retry transient failures (429, 5xx) up to this many times")
+	rootCmd.PersistentFlags().DurationVar(&retryMaxWait, "retry-max-wait", 30*time.Second, "Maximum wait between retries")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Cache render responses in this directory")
+	rootCmd.PersistentFlags().DurationVar(&cacheTTL, "cache-ttl", 24*time.Hour, "How long cached responses are served")
+	rootCmd.PersistentFlags().Int64Var(&cacheMaxBytes, "cache-max-bytes", capture.DefaultCacheMaxBytes, "Maximum total size of the response cache")
 }
 
 func newCaptureClient() *capture.Capture {
 	httpClient := &http.Client{
-		Timeout: timeout,
+		Timeout:   timeout,
+		Transport: cassetteTransport(http.DefaultTransport),
 	}
 
 	var opts []capture.Option
@@ -75,7 +157,33 @@ func newCaptureClient() *capture.Capture {
 	if useEdge {
 		opts = append(opts, capture.WithEdge())
 	}
-	return capture.New(captureKey, captureSecret, opts...)
+	if cacheDir != "" {
+		cache := capture.NewDiskCache(cacheDir, cacheTTL)
+		cache.MaxBytes = cacheMaxBytes
+		opts = append(opts, func(c *capture.Capture) { c.Cache = cache })
+	}
+	if retries > 0 {
+		opts = append(opts, capture.WithRetryPolicy(capture.RetryPolicy{
+			MaxAttempts: retries + 1,
+			MaxBackoff:  retryMaxWait,
+			Jitter:      0.2,
+			OnRetry: func(attempt capture.RetryAttempt) {
+				verboseLog("Attempt %d failed (status %d, error %v), retrying in %s", attempt.Attempt, attempt.StatusCode, attempt.Err, attempt.Wait)
+			},
+		}))
+	}
+	if recorder := sessionsTraceRecorder(); recorder != nil {
+		opts = append(opts, capture.WithRecorder(recorder))
+	}
+	c := capture.New(captureKey, captureSecret, opts...)
+	if apiURL := os.Getenv("CAPTURE_API_URL"); apiURL != "" {
+		c.APIURL = apiURL
+		c.EdgeURL = apiURL
+	}
+	if sessionsURL := os.Getenv("CAPTURE_SESSIONS_URL"); sessionsURL != "" {
+		c.SessionsURL = sessionsURL
+	}
+	return c
 }
 
 func verboseLog(format string, args ...interface{}) {
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 )
@@ -56,10 +57,12 @@ func runScreenshot(cmd *cobra.Command, args []string) error {
 
 	verboseLog("Capturing screenshot of %s", targetURL)
 
-	data, err := client.FetchImage(targetURL, opts)
+	err = streamOutput(screenshotOutput, func(w io.Writer) (int64, error) {
+		return client.FetchImageToContext(cmd.Context(), w, targetURL, opts)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to capture screenshot: %w", err)
 	}
 
-	return writeOutput(data, screenshotOutput)
+	return nil
 }
@@ -3,6 +3,7 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 	capture "github.com/techulus/capture-go"
@@ -14,7 +15,16 @@ var sessionsCmd = &cobra.Command{
 	Long: `Manage stateful Capture browser sessions.
 
 Sessions use the Capture Sessions API and bearer authentication derived from
-CAPTURE_KEY and CAPTURE_SECRET.`,
+CAPTURE_KEY and CAPTURE_SECRET.
+
+Failed requests exit with a status describing the failure:
+  3  unauthorized
+  4  rate limited
+  5  session not found
+  6  session expired
+  7  invalid action
+  8  session closed
+  1  any other error`,
 }
 
 var sessionsCreateCmd = &cobra.Command{
@@ -31,10 +41,17 @@ var sessionsGetCmd = &cobra.Command{
 }
 
 var sessionsCloseCmd = &cobra.Command{
-	Use:   "close <session-id>",
+	Use:   "close [session-id]",
 	Short: "Close a browser session",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runSessionsClose,
+	Long: `Close a browser session by ID, or with --all or --expired the sessions
+recorded by this CLI (see "sessions list").
+
+Examples:
+  capture sessions close sess_123
+  capture sessions close --expired
+  capture sessions close --all`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSessionsClose,
 }
 
 var sessionsActionCmd = &cobra.Command{
@@ -52,6 +69,8 @@ var (
 	sessionsPretty            bool
 	sessionActionOptions      []string
 	sessionActionPayloadJSON  string
+	sessionCloseAll           bool
+	sessionCloseExpired       bool
 )
 
 func init() {
@@ -66,6 +85,9 @@ func init() {
 
 	sessionsGetCmd.Flags().BoolVar(&sessionsPretty, "pretty", false, "Pretty print JSON output")
 	sessionsCloseCmd.Flags().BoolVar(&sessionsPretty, "pretty", false, "Pretty print JSON output")
+	sessionsCloseCmd.Flags().BoolVar(&sessionCloseAll, "all", false, "Close every session recorded by the CLI")
+	sessionsCloseCmd.Flags().BoolVar(&sessionCloseExpired, "expired", false, "Close recorded sessions that expired or are no longer active")
+	sessionsCloseCmd.MarkFlagsMutuallyExclusive("all", "expired")
 
 	sessionsActionCmd.Flags().StringArrayVarP(&sessionActionOptions, "option", "X", nil, "Action payload option as key=value (can be repeated)")
 	sessionsActionCmd.Flags().StringVar(&sessionActionPayloadJSON, "payload-json", "", "Action payload as a JSON object")
@@ -94,10 +116,13 @@ func runSessionsCreate(cmd *cobra.Command, args []string) error {
 		return emitJSON(client.BuildCreateSessionRequest(options), sessionsPretty)
 	}
 
-	response, err := client.CreateSession(options)
+	response, err := client.CreateSessionContext(cmd.Context(), options)
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
+	if session, err := response.Session(); err == nil {
+		rememberSession(session, "create")
+	}
 
 	return emitJSON(response, sessionsPretty)
 }
@@ -108,7 +133,7 @@ func runSessionsGet(cmd *cobra.Command, args []string) error {
 		return emitJSON(client.BuildGetSessionRequest(args[0]), sessionsPretty)
 	}
 
-	response, err := client.GetSession(args[0])
+	response, err := client.GetSessionContext(cmd.Context(), args[0])
 	if err != nil {
 		return fmt.Errorf("failed to get session: %w", err)
 	}
@@ -117,19 +142,63 @@ func runSessionsGet(cmd *cobra.Command, args []string) error {
 }
 
 func runSessionsClose(cmd *cobra.Command, args []string) error {
+	bulk := sessionCloseAll || sessionCloseExpired
+	if bulk == (len(args) == 1) {
+		return fmt.Errorf("specify a session ID or one of --all, --expired")
+	}
+
 	client := newCaptureClient()
+	if bulk {
+		return runSessionsCloseRegistered(cmd, client)
+	}
 	if dryRun {
 		return emitJSON(client.BuildCloseSessionRequest(args[0]), sessionsPretty)
 	}
 
-	response, err := client.CloseSession(args[0])
+	response, err := client.CloseSessionContext(cmd.Context(), args[0])
 	if err != nil {
+		if sessionGone(err) {
+			forgetSessions(args[0])
+		}
 		return fmt.Errorf("failed to close session: %w", err)
 	}
+	forgetSessions(args[0])
 
 	return emitJSON(response, sessionsPretty)
 }
 
+func runSessionsCloseRegistered(cmd *cobra.Command, client *capture.Capture) error {
+	registry, err := openSessionRegistry()
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		sessions, err := registry.load()
+		if err != nil {
+			return err
+		}
+		previews := []capture.SessionRequestPreview{}
+		now := time.Now()
+		for _, session := range sessions {
+			if sessionCloseExpired && !session.expired(now) {
+				continue
+			}
+			previews = append(previews, client.BuildCloseSessionRequest(session.ID))
+		}
+		return emitJSON(previews, sessionsPretty)
+	}
+
+	summary, closeErr := closeRegistered(cmd.Context(), client, registry, sessionCloseExpired)
+	if summary == nil {
+		return closeErr
+	}
+	if err := emitJSON(summary, sessionsPretty); err != nil {
+		return err
+	}
+	return closeErr
+}
+
 func runSessionsAction(cmd *cobra.Command, args []string) error {
 	payload, err := parseActionPayload(sessionActionPayloadJSON, sessionActionOptions)
 	if err != nil {
@@ -141,7 +210,7 @@ func runSessionsAction(cmd *cobra.Command, args []string) error {
 		return emitJSON(client.BuildExecuteActionRequest(args[0], args[1], payload), sessionsPretty)
 	}
 
-	response, err := client.ExecuteAction(args[0], args[1], payload)
+	response, err := client.ExecuteActionContext(cmd.Context(), args[0], args[1], payload)
 	if err != nil {
 		return fmt.Errorf("failed to execute action: %w", err)
 	}
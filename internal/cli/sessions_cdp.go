@@ -0,0 +1,348 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	capture "github.com/techulus/capture-go"
+)
+
+var sessionsCDPProxyCmd = &cobra.Command{
+	Use:   "cdp-proxy",
+	Short: "Expose a CDP session as a local DevTools endpoint",
+	Long: `Create a Chrome DevTools Protocol session and serve it on a local
+DevTools-compatible endpoint, so tools that expect a browser on
+127.0.0.1:9222 can drive it. WebSocket connections are forwarded to the
+session's connectUrl, and /json/version answers endpoint discovery.
+
+Like Chrome's DevTools server, the proxy only answers requests whose Host is
+localhost or a loopback address, and rejects requests from web pages (with
+an Origin header) unless the origin is allowed with --allow-origin.
+
+The session is closed when the proxy stops (Ctrl-C) or its TTL is reached.
+
+Examples:
+  capture sessions cdp-proxy
+  capture sessions cdp-proxy --listen 127.0.0.1:9333 --max-ttl-seconds 1800
+  capture sessions cdp-proxy --allow-origin http://localhost:3000
+
+  # then, e.g. with Playwright
+  chromium.connectOverCDP("http://127.0.0.1:9222")`,
+	Args: cobra.NoArgs,
+	RunE: runSessionsCDPProxy,
+}
+
+var (
+	sessionCDPListen       string
+	sessionCDPAllowOrigins []string
+)
+
+func init() {
+	sessionsCmd.AddCommand(sessionsCDPProxyCmd)
+
+	sessionsCDPProxyCmd.Flags().StringVar(&sessionCDPListen, "listen", "127.0.0.1:9222", "Local address to serve the DevTools endpoint on")
+	sessionsCDPProxyCmd.Flags().StringSliceVar(&sessionCDPAllowOrigins, "allow-origin", nil, "Origin allowed to connect from a web page, or * for any (repeatable)")
+	sessionsCDPProxyCmd.Flags().IntVar(&sessionMaxTTLSeconds, "max-ttl-seconds", 0, "Maximum session lifetime in seconds")
+}
+
+func runSessionsCDPProxy(cmd *cobra.Command, args []string) error {
+	client := newCaptureClient()
+	options := &capture.CreateSessionOptions{MaxTtlSeconds: sessionMaxTTLSeconds, CDP: true}
+	if dryRun {
+		return emitJSON(client.BuildCreateSessionRequest(options), sessionsPretty)
+	}
+
+	listener, err := net.Listen("tcp", sessionCDPListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", sessionCDPListen, err)
+	}
+	defer listener.Close()
+
+	ctx := cmd.Context()
+	session, err := client.CreateSessionTypedContext(ctx, options)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	rememberSession(session, "cdp-proxy")
+	defer closeCLISession(session, os.Stderr)
+
+	if session.ConnectURL == "" {
+		return fmt.Errorf("session %s did not return a connectUrl", session.ID)
+	}
+
+	proxy := newCDPProxy(session.ID, session.ConnectURL, sessionCDPAllowOrigins)
+	server := &http.Server{Handler: proxy}
+
+	if !session.ExpiresAt.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, session.ExpiresAt)
+		defer cancel()
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+		proxy.closeConnections()
+	}()
+
+	fmt.Fprintf(os.Stderr, "DevTools listening on ws://%s%s\n", listener.Addr(), proxy.browserPath())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("cdp proxy failed: %w", err)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		fmt.Fprintf(os.Stderr, "Session %s reached its TTL\n", session.ID)
+	}
+	return nil
+}
+
+// cdpProxy serves the DevTools discovery routes and forwards WebSocket
+// connections to a remote CDP endpoint. Frames are relayed as raw bytes, so
+// extensions negotiated with the remote end keep working.
+type cdpProxy struct {
+	sessionID      string
+	connectURL     string
+	allowedOrigins []string
+	dialer         *net.Dialer
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func newCDPProxy(sessionID, connectURL string, allowedOrigins []string) *cdpProxy {
+	return &cdpProxy{
+		sessionID:      sessionID,
+		connectURL:     connectURL,
+		allowedOrigins: allowedOrigins,
+		dialer:         &net.Dialer{Timeout: 30 * time.Second},
+		conns:          map[net.Conn]struct{}{},
+	}
+}
+
+func (p *cdpProxy) browserPath() string {
+	return "/devtools/browser/" + url.PathEscape(p.sessionID)
+}
+
+func (p *cdpProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := p.checkRequest(r); err != nil {
+		verboseLog("CDP proxy rejected request from %s: %v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if isWebSocketUpgrade(r) {
+		p.forward(w, r)
+		return
+	}
+
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/json/version":
+		writeJSONResponse(w, map[string]string{
+			"Browser":              "Capture/" + p.sessionID,
+			"Protocol-Version":     "1.3",
+			"webSocketDebuggerUrl": "ws://" + r.Host + p.browserPath(),
+		})
+	case "/json", "/json/list":
+		// Targets are discovered over the browser WebSocket instead.
+		writeJSONResponse(w, []interface{}{})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// checkRequest applies the checks of Chrome's DevTools server: the Host must
+// name the local machine, which defeats DNS rebinding, and browser requests
+// must come from an allowed Origin, so web pages cannot drive the session.
+func (p *cdpProxy) checkRequest(r *http.Request) error {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if ip := net.ParseIP(host); !strings.EqualFold(host, "localhost") && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("host %q is not allowed; connect through localhost or 127.0.0.1", r.Host)
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	for _, allowed := range p.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q is not allowed; use --allow-origin to allow it", origin)
+}
+
+func writeJSONResponse(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// forward performs the WebSocket handshake with the remote endpoint on the
+// client's behalf and then pipes both connections until either side closes.
+func (p *cdpProxy) forward(w http.ResponseWriter, r *http.Request) {
+	upstream, upstreamReader, response, err := p.dialUpstream(r)
+	if err != nil {
+		verboseLog("CDP upstream connection failed: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 64<<10))
+		verboseLog("CDP upstream refused the handshake with status %d", response.StatusCode)
+		http.Error(w, strings.TrimSpace(string(body)), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection does not support hijacking", http.StatusInternalServerError)
+		return
+	}
+	client, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		verboseLog("CDP client hijack failed: %v", err)
+		return
+	}
+	defer client.Close()
+
+	p.track(client, upstream)
+	defer p.untrack(client, upstream)
+
+	if _, err := fmt.Fprintf(clientBuf, "HTTP/1.1 101 Switching Protocols\r\n"); err != nil {
+		return
+	}
+	if err := response.Header.Write(clientBuf); err != nil {
+		return
+	}
+	if _, err := clientBuf.WriteString("\r\n"); err != nil {
+		return
+	}
+	if err := clientBuf.Flush(); err != nil {
+		return
+	}
+	verboseLog("CDP client %s connected", client.RemoteAddr())
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, clientBuf.Reader)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, upstreamReader)
+		done <- struct{}{}
+	}()
+	<-done
+	verboseLog("CDP client %s disconnected", client.RemoteAddr())
+}
+
+// dialUpstream connects to the remote CDP endpoint and sends the client's
+// handshake, rewritten for the remote host and path.
+func (p *cdpProxy) dialUpstream(r *http.Request) (net.Conn, *bufio.Reader, *http.Response, error) {
+	target, err := url.Parse(p.connectURL)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid connectUrl: %w", err)
+	}
+
+	host := target.Host
+	var conn net.Conn
+	switch target.Scheme {
+	case "wss", "https":
+		if target.Port() == "" {
+			host = net.JoinHostPort(target.Hostname(), "443")
+		}
+		tlsDialer := &tls.Dialer{NetDialer: p.dialer, Config: &tls.Config{ServerName: target.Hostname()}}
+		conn, err = tlsDialer.DialContext(r.Context(), "tcp", host)
+	case "ws", "http":
+		if target.Port() == "" {
+			host = net.JoinHostPort(target.Hostname(), "80")
+		}
+		conn, err = p.dialer.DialContext(r.Context(), "tcp", host)
+	default:
+		return nil, nil, nil, fmt.Errorf("unsupported connectUrl scheme %q", target.Scheme)
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to connect to %s: %w", target.Host, err)
+	}
+
+	request := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: target.Path, RawPath: target.RawPath, RawQuery: target.RawQuery},
+		Host:       target.Host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+	}
+	if request.URL.Path == "" {
+		request.URL.Path = "/"
+	}
+	for _, key := range []string{"Upgrade", "Connection", "Sec-WebSocket-Key", "Sec-WebSocket-Version", "Sec-WebSocket-Extensions", "Sec-WebSocket-Protocol"} {
+		if values := r.Header.Values(key); len(values) > 0 {
+			request.Header[key] = values
+		}
+	}
+	request.Header.Set("User-Agent", "capture-cli")
+
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if err := request.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, nil, fmt.Errorf("failed to send handshake: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, fmt.Errorf("failed to read handshake response: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, reader, response, nil
+}
+
+func (p *cdpProxy) track(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+}
+
+func (p *cdpProxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+// closeConnections drops forwarded connections, which http.Server.Shutdown
+// does not track once they are hijacked.
+func (p *cdpProxy) closeConnections() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newEchoWebSocketServer accepts a WebSocket handshake and then echoes raw
+// bytes back, which is enough to verify the proxy relays frames untouched.
+func newEchoWebSocketServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cdp" || r.URL.Query().Get("token") != "abc" {
+			http.Error(w, "bad path "+r.URL.String(), http.StatusForbidden)
+			return
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprintf(buf, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", r.Header.Get("Sec-WebSocket-Key"))
+		buf.Flush()
+		_, _ = io.Copy(conn, buf)
+	}))
+}
+
+func dialWebSocket(t *testing.T, addr, path string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGVzdA==\r\nSec-WebSocket-Version: 13\r\n\r\n", path, addr)
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake: %v", err)
+	}
+	return conn, reader, response
+}
+
+func TestCDPProxyForwardsWebSocket(t *testing.T) {
+	upstream := newEchoWebSocketServer(t)
+	defer upstream.Close()
+
+	proxy := newCDPProxy("sess_1", "ws"+strings.TrimPrefix(upstream.URL, "http")+"/cdp?token=abc", nil)
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+	defer proxy.closeConnections()
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	conn, reader, response := dialWebSocket(t, addr, "/devtools/browser/sess_1")
+	defer conn.Close()
+
+	if response.StatusCode != http.StatusSwitchingProtocols || response.Header.Get("Sec-WebSocket-Accept") != "dGVzdA==" {
+		t.Fatalf("unexpected handshake: %d %v", response.StatusCode, response.Header)
+	}
+
+	frame := []byte{0x81, 0x85, 1, 2, 3, 4, 'h' ^ 1, 'e' ^ 2, 'l' ^ 3, 'l' ^ 4, 'o' ^ 1}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	echoed := make([]byte, len(frame))
+	if _, err := io.ReadFull(reader, echoed); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(echoed) != string(frame) {
+		t.Fatalf("frame was modified: %v", echoed)
+	}
+}
+
+func TestCDPProxyRejectedHandshake(t *testing.T) {
+	upstream := newEchoWebSocketServer(t)
+	defer upstream.Close()
+
+	proxy := newCDPProxy("sess_1", "ws"+strings.TrimPrefix(upstream.URL, "http")+"/cdp?token=wrong", nil)
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	conn, _, response := dialWebSocket(t, strings.TrimPrefix(server.URL, "http://"), "/devtools/browser/sess_1")
+	defer conn.Close()
+	if response.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502 for a refused upstream handshake, got %d", response.StatusCode)
+	}
+}
+
+func TestCDPProxyVersion(t *testing.T) {
+	server := httptest.NewServer(newCDPProxy("sess_1", "wss://cdp.example.com/cdp", nil))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/json/version")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer response.Body.Close()
+
+	var version map[string]string
+	if err := json.NewDecoder(response.Body).Decode(&version); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := "ws://" + strings.TrimPrefix(server.URL, "http://") + "/devtools/browser/sess_1"
+	if version["webSocketDebuggerUrl"] != want {
+		t.Fatalf("webSocketDebuggerUrl = %q, want %q", version["webSocketDebuggerUrl"], want)
+	}
+}
+
+func TestCDPProxyRejectsForeignOriginsAndHosts(t *testing.T) {
+	proxy := newCDPProxy("sess_1", "wss://cdp.example.com/cdp", []string{"http://localhost:3000"})
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	cases := []struct {
+		host, origin string
+		want         int
+	}{
+		{"", "", http.StatusOK},
+		{"localhost:9222", "", http.StatusOK},
+		{"[::1]:9222", "", http.StatusOK},
+		{"", "http://localhost:3000", http.StatusOK},
+		{"", "https://evil.example.com", http.StatusForbidden},
+		{"evil.example.com:9222", "", http.StatusForbidden},
+		{"192.168.1.10:9222", "", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/json/version", nil)
+		if tc.host != "" {
+			req.Host = tc.host
+		}
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != tc.want {
+			t.Errorf("host %q origin %q: status %d, want %d", tc.host, tc.origin, response.StatusCode, tc.want)
+		}
+	}
+
+	addr := strings.TrimPrefix(server.URL, "http://")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /devtools/browser/sess_1 HTTP/1.1\r\nHost: %s\r\nOrigin: https://evil.example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGVzdA==\r\nSec-WebSocket-Version: 13\r\n\r\n", addr)
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake: %v", err)
+	}
+	if response.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected WebSocket upgrade from a foreign origin to be refused, got %d", response.StatusCode)
+	}
+}
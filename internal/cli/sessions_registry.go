@@ -0,0 +1,448 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	capture "github.com/techulus/capture-go"
+)
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sessions created by this CLI",
+	Long: `List the sessions created by "sessions create", "sessions run" and
+"sessions shell" that have not been closed through the CLI. Each session's
+status is refreshed with the Sessions API.
+
+Sessions are recorded in a local state file, by default under the user
+config directory; use --state-file to choose another location.
+
+Examples:
+  capture sessions list
+  capture sessions list --json
+  capture sessions close --expired`,
+	Args: cobra.NoArgs,
+	RunE: runSessionsList,
+}
+
+var (
+	sessionsStateFile string
+	sessionsListJSON  bool
+)
+
+func init() {
+	sessionsCmd.AddCommand(sessionsListCmd)
+
+	sessionsCmd.PersistentFlags().StringVar(&sessionsStateFile, "state-file", "", "File recording sessions created by the CLI (default: user config directory)")
+	sessionsListCmd.Flags().BoolVar(&sessionsListJSON, "json", false, "Output sessions as JSON")
+}
+
+// Statuses recorded for sessions the API no longer knows about.
+const (
+	sessionStatusNotFound = "not_found"
+	sessionStatusExpired  = "expired"
+)
+
+// registeredSession is a session recorded in the local state file.
+type registeredSession struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status,omitempty"`
+	CDP       bool      `json:"cdp,omitempty"`
+	Command   string    `json:"command,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// expired reports whether the session can no longer be used.
+func (s registeredSession) expired(now time.Time) bool {
+	if !s.ExpiresAt.IsZero() && now.After(s.ExpiresAt) {
+		return true
+	}
+	switch s.Status {
+	case "", "active":
+		return false
+	}
+	return true
+}
+
+// sessionRegistry persists the sessions the CLI has created so they can be
+// listed and closed later.
+type sessionRegistry struct {
+	path string
+}
+
+func openSessionRegistry() (*sessionRegistry, error) {
+	path := sessionsStateFile
+	if path == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate config directory (use --state-file): %w", err)
+		}
+		path = filepath.Join(configDir, "capture", "sessions.json")
+	}
+	return &sessionRegistry{path: path}, nil
+}
+
+func (r *sessionRegistry) load() ([]registeredSession, error) {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read session state: %w", err)
+	}
+
+	var sessions []registeredSession
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to parse session state %s: %w", r.path, err)
+	}
+	return sessions, nil
+}
+
+// save replaces the state file atomically, so readers never observe a
+// partially written file. Use update to change the state.
+func (r *sessionRegistry) save(sessions []registeredSession) error {
+	if sessions == nil {
+		sessions = []registeredSession{}
+	}
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("failed to create session state directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), ".sessions-*")
+	if err != nil {
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	return nil
+}
+
+// update loads the state, applies fn and saves the result while holding the
+// state lock, so concurrent CLI processes do not lose each other's updates.
+func (r *sessionRegistry) update(fn func([]registeredSession) []registeredSession) error {
+	unlock, err := r.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	sessions, err := r.load()
+	if err != nil {
+		return err
+	}
+	return r.save(fn(sessions))
+}
+
+const (
+	registryLockTimeout = 10 * time.Second
+	registryLockStale   = 30 * time.Second
+	registryLockRetry   = 10 * time.Millisecond
+)
+
+// lock takes an exclusive lock on the state file by creating a lock file
+// next to it. A lock file older than registryLockStale is assumed to be left
+// behind by a crashed process and is broken.
+func (r *sessionRegistry) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session state directory: %w", err)
+	}
+
+	lockPath := r.path + ".lock"
+	deadline := time.Now().Add(registryLockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock session state: %w", err)
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > registryLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for session state lock %s", lockPath)
+		}
+		time.Sleep(registryLockRetry)
+	}
+}
+
+func (r *sessionRegistry) add(session *capture.Session, command string) error {
+	entry := registeredSession{
+		ID:        session.ID,
+		Status:    session.Status,
+		CDP:       session.CDP,
+		Command:   command,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+		CheckedAt: time.Now().UTC(),
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = entry.CheckedAt
+	}
+	if entry.ExpiresAt.IsZero() && session.MaxTtlSeconds > 0 {
+		entry.ExpiresAt = entry.CreatedAt.Add(time.Duration(session.MaxTtlSeconds) * time.Second)
+	}
+
+	return r.update(func(sessions []registeredSession) []registeredSession {
+		return append(removeRegistered(sessions, session.ID), entry)
+	})
+}
+
+// remove drops ids from the state. The state file is left alone, and not
+// created, when it records none of them, as for sessions created outside
+// the CLI.
+func (r *sessionRegistry) remove(ids ...string) error {
+	sessions, err := r.load()
+	if err != nil {
+		return err
+	}
+	if !containsRegistered(sessions, ids...) {
+		return nil
+	}
+	return r.update(func(sessions []registeredSession) []registeredSession {
+		return removeRegistered(sessions, ids...)
+	})
+}
+
+func containsRegistered(sessions []registeredSession, ids ...string) bool {
+	for _, session := range sessions {
+		for _, id := range ids {
+			if session.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func removeRegistered(sessions []registeredSession, ids ...string) []registeredSession {
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := sessions[:0]
+	for _, session := range sessions {
+		if !drop[session.ID] {
+			kept = append(kept, session)
+		}
+	}
+	return kept
+}
+
+// rememberSession records a session created by command. Failing to record
+// it only warns, since the session itself was created successfully.
+func rememberSession(session *capture.Session, command string) {
+	registry, err := openSessionRegistry()
+	if err == nil {
+		err = registry.add(session, command)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record session %s: %v\n", session.ID, err)
+	}
+}
+
+// forgetSessions drops closed sessions from the local state.
+func forgetSessions(ids ...string) {
+	registry, err := openSessionRegistry()
+	if err == nil {
+		err = registry.remove(ids...)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update session state: %v\n", err)
+	}
+}
+
+// sessionCloseTimeout bounds closing a session when a command ends.
+const sessionCloseTimeout = 30 * time.Second
+
+// closeCLISession closes a session the command created and drops it from
+// the local state. It does not use the command context, which may already
+// be canceled, so the session does not linger until its TTL. The outcome
+// is reported to log unless log is nil.
+func closeCLISession(session *capture.Session, log io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionCloseTimeout)
+	defer cancel()
+	if err := session.CloseContext(ctx); err != nil {
+		if log != nil {
+			fmt.Fprintf(log, "Failed to close session %s: %v\n", session.ID, err)
+		}
+		return err
+	}
+	forgetSessions(session.ID)
+	if log != nil {
+		fmt.Fprintf(log, "Closed session %s\n", session.ID)
+	}
+	return nil
+}
+
+// sessionGone reports whether err means the session no longer exists, so
+// closing it again is unnecessary.
+func sessionGone(err error) bool {
+	return errors.Is(err, capture.ErrSessionNotFound) || errors.Is(err, capture.ErrSessionExpired) || errors.Is(err, capture.ErrSessionClosed)
+}
+
+// refreshRegistered updates each session's status from the API. Sessions the
+// API no longer knows are marked as not found or expired.
+func refreshRegistered(ctx context.Context, client *capture.Capture, sessions []registeredSession) ([]registeredSession, error) {
+	for i := range sessions {
+		entry := &sessions[i]
+		session, err := client.GetSessionTypedContext(ctx, entry.ID)
+		switch {
+		case err == nil:
+			entry.Status = session.Status
+			if !session.ExpiresAt.IsZero() {
+				entry.ExpiresAt = session.ExpiresAt
+			}
+		case errors.Is(err, capture.ErrSessionExpired):
+			entry.Status = sessionStatusExpired
+		case errors.Is(err, capture.ErrSessionNotFound):
+			entry.Status = sessionStatusNotFound
+		default:
+			return sessions, fmt.Errorf("failed to get session %s: %w", entry.ID, err)
+		}
+		entry.CheckedAt = time.Now().UTC()
+	}
+	return sessions, nil
+}
+
+func runSessionsList(cmd *cobra.Command, args []string) error {
+	registry, err := openSessionRegistry()
+	if err != nil {
+		return err
+	}
+	sessions, err := registry.load()
+	if err != nil {
+		return err
+	}
+
+	if !dryRun && len(sessions) > 0 {
+		sessions, err = refreshRegistered(cmd.Context(), newCaptureClient(), sessions)
+		if err != nil {
+			return err
+		}
+		refreshed := sessions
+		if err := registry.update(func(current []registeredSession) []registeredSession {
+			return mergeRegistered(current, refreshed)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if sessionsListJSON {
+		if sessions == nil {
+			sessions = []registeredSession{}
+		}
+		return emitJSON(sessions, true)
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tCOMMAND\tAGE\tEXPIRES IN")
+	for _, session := range sessions {
+		expiresIn := "-"
+		if !session.ExpiresAt.IsZero() {
+			expiresIn = session.ExpiresAt.Sub(now).Round(time.Second).String()
+			if session.ExpiresAt.Before(now) {
+				expiresIn = "expired"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", session.ID, session.Status, session.Command, now.Sub(session.CreatedAt).Round(time.Second), expiresIn)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%d sessions in %s\n", len(sessions), registry.path)
+	return nil
+}
+
+// mergeRegistered applies refreshed entries to the current state, keeping
+// sessions another process added or removed in the meantime.
+func mergeRegistered(current, refreshed []registeredSession) []registeredSession {
+	byID := make(map[string]registeredSession, len(refreshed))
+	for _, session := range refreshed {
+		byID[session.ID] = session
+	}
+	for i, session := range current {
+		if updated, ok := byID[session.ID]; ok {
+			current[i] = updated
+		}
+	}
+	return current
+}
+
+// sessionCloseSummary is printed by `sessions close --all/--expired`.
+type sessionCloseSummary struct {
+	Closed []string             `json:"closed"`
+	Failed []sessionCloseFailed `json:"failed,omitempty"`
+}
+
+type sessionCloseFailed struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// closeRegistered closes the registered sessions selected by --all or
+// --expired and forgets the ones that are gone. It returns the first
+// failure, so the exit code reflects it.
+func closeRegistered(ctx context.Context, client *capture.Capture, registry *sessionRegistry, expiredOnly bool) (*sessionCloseSummary, error) {
+	sessions, err := registry.load()
+	if err != nil {
+		return nil, err
+	}
+	if expiredOnly {
+		if sessions, err = refreshRegistered(ctx, client, sessions); err != nil {
+			return nil, err
+		}
+	}
+
+	summary := &sessionCloseSummary{Closed: []string{}}
+	var firstErr error
+	now := time.Now()
+	for _, session := range sessions {
+		if expiredOnly && !session.expired(now) {
+			continue
+		}
+		if _, err := client.CloseSessionContext(ctx, session.ID); err != nil && !sessionGone(err) {
+			summary.Failed = append(summary.Failed, sessionCloseFailed{ID: session.ID, Error: err.Error()})
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to close session %s: %w", session.ID, err)
+			}
+			continue
+		}
+		summary.Closed = append(summary.Closed, session.ID)
+	}
+
+	if err := registry.remove(summary.Closed...); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return summary, firstErr
+}
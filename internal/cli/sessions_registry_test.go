@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func useTempSessionRegistry(t *testing.T) *sessionRegistry {
+	t.Helper()
+	prev := sessionsStateFile
+	sessionsStateFile = filepath.Join(t.TempDir(), "state", "sessions.json")
+	t.Cleanup(func() { sessionsStateFile = prev })
+
+	registry, err := openSessionRegistry()
+	if err != nil {
+		t.Fatalf("openSessionRegistry() error: %v", err)
+	}
+	return registry
+}
+
+func registeredIDs(t *testing.T, registry *sessionRegistry) []string {
+	t.Helper()
+	sessions, err := registry.load()
+	if err != nil {
+		t.Fatalf("load() error: %v", err)
+	}
+	ids := []string{}
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	return ids
+}
+
+func TestSessionRegistry(t *testing.T) {
+	registry := useTempSessionRegistry(t)
+
+	if ids := registeredIDs(t, registry); len(ids) != 0 {
+		t.Fatalf("expected empty registry, got %v", ids)
+	}
+
+	rememberSession(&capture.Session{ID: "sess_1", Status: "active", MaxTtlSeconds: 60}, "create")
+	rememberSession(&capture.Session{ID: "sess_2", Status: "active"}, "run")
+	rememberSession(&capture.Session{ID: "sess_1", Status: "active"}, "create")
+	if ids := registeredIDs(t, registry); !reflect.DeepEqual(ids, []string{"sess_2", "sess_1"}) {
+		t.Fatalf("unexpected sessions: %v", ids)
+	}
+
+	forgetSessions("sess_2", "sess_missing")
+	sessions, err := registry.load()
+	if err != nil {
+		t.Fatalf("load() error: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "sess_1" || sessions[0].Command != "create" {
+		t.Fatalf("unexpected sessions: %#v", sessions)
+	}
+}
+
+func TestForgetUnknownSessionLeavesStateAlone(t *testing.T) {
+	registry := useTempSessionRegistry(t)
+
+	forgetSessions("sess_dashboard")
+	for _, path := range []string{registry.path, registry.path + ".lock"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s not to be created, got %v", path, err)
+		}
+	}
+
+	rememberSession(&capture.Session{ID: "sess_1"}, "create")
+	before, err := os.Stat(registry.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forgetSessions("sess_dashboard")
+	after, err := os.Stat(registry.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Fatal("expected the state file not to be rewritten")
+	}
+}
+
+func TestSessionRegistryConcurrentUpdates(t *testing.T) {
+	registry := useTempSessionRegistry(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate registries behave like separate CLI processes.
+			other := &sessionRegistry{path: registry.path}
+			if err := other.add(&capture.Session{ID: fmt.Sprintf("sess_%d", i)}, "create"); err != nil {
+				t.Errorf("add() error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if ids := registeredIDs(t, registry); len(ids) != 20 {
+		t.Fatalf("expected 20 sessions, got %d: %v", len(ids), ids)
+	}
+	if _, err := os.Stat(registry.path + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("expected the lock file to be removed, got %v", err)
+	}
+}
+
+func TestSessionRegistryBreaksStaleLock(t *testing.T) {
+	registry := useTempSessionRegistry(t)
+	lockPath := registry.path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * registryLockStale)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.add(&capture.Session{ID: "sess_1"}, "create"); err != nil {
+		t.Fatalf("add() with a stale lock: %v", err)
+	}
+}
+
+func TestRegisteredSessionExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		session registeredSession
+		want    bool
+	}{
+		{registeredSession{Status: "active", ExpiresAt: now.Add(time.Minute)}, false},
+		{registeredSession{Status: "active", ExpiresAt: now.Add(-time.Minute)}, true},
+		{registeredSession{}, false},
+		{registeredSession{Status: sessionStatusNotFound}, true},
+		{registeredSession{Status: "closed"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.session.expired(now); got != tt.want {
+			t.Errorf("expired(%#v) = %v, want %v", tt.session, got, tt.want)
+		}
+	}
+}
+
+func TestCloseRegistered(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		id := strings.TrimPrefix(r.URL.Path, "/v1/sessions/")
+		switch id {
+		case "sess_gone":
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "Session not found"})
+			return
+		case "sess_broken":
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		if r.Method == http.MethodDelete {
+			closed = append(closed, id)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"session": map[string]interface{}{"id": id, "status": "active"}})
+	}))
+	defer server.Close()
+
+	client := capture.New("key", "secret")
+	client.SessionsURL = server.URL
+	registry := useTempSessionRegistry(t)
+	if err := registry.save([]registeredSession{
+		{ID: "sess_live", Status: "active"},
+		{ID: "sess_gone", Status: "active"},
+		{ID: "sess_old", Status: "active", ExpiresAt: time.Now().Add(-time.Minute)},
+		{ID: "sess_broken", Status: "active"},
+	}); err != nil {
+		t.Fatalf("save() error: %v", err)
+	}
+
+	summary, err := closeRegistered(context.Background(), client, registry, true)
+	if err != nil {
+		t.Fatalf("closeRegistered(expired) error: %v", err)
+	}
+	if !reflect.DeepEqual(summary.Closed, []string{"sess_gone", "sess_old"}) {
+		t.Fatalf("unexpected closed sessions: %v", summary.Closed)
+	}
+	if ids := registeredIDs(t, registry); !reflect.DeepEqual(ids, []string{"sess_live", "sess_broken"}) {
+		t.Fatalf("unexpected remaining sessions: %v", ids)
+	}
+
+	summary, err = closeRegistered(context.Background(), client, registry, false)
+	if err == nil || ExitCode(err) != ExitError {
+		t.Fatalf("expected close failure for sess_broken, got %v", err)
+	}
+	if !reflect.DeepEqual(summary.Closed, []string{"sess_live"}) || len(summary.Failed) != 1 || summary.Failed[0].ID != "sess_broken" {
+		t.Fatalf("unexpected summary: %#v", summary)
+	}
+	if ids := registeredIDs(t, registry); !reflect.DeepEqual(ids, []string{"sess_broken"}) {
+		t.Fatalf("unexpected remaining sessions: %v", ids)
+	}
+	if !reflect.DeepEqual(closed, []string{"sess_old", "sess_live"}) {
+		t.Fatalf("unexpected close requests: %v", closed)
+	}
+}
@@ -0,0 +1,453 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	capture "github.com/techulus/capture-go"
+	"gopkg.in/yaml.v3"
+)
+
+var sessionsRunCmd = &cobra.Command{
+	Use:   "run <script>",
+	Short: "Run a scripted list of actions in a new session",
+	Long: `Create a browser session, run the actions declared in a YAML (or JSON)
+script, save returned artifacts and close the session, even when a step fails
+or the command is interrupted.
+
+  session:
+    maxTtlSeconds: 300
+  vars:
+    base: https://example.com
+  steps:
+    - name: open
+      action: goto
+      payload: {url: "${base}/login"}
+    - action: type
+      payload: {selector: "#email", text: "${email}"}
+    - action: evaluate
+      payload: {expression: "document.title"}
+      set: title
+    - action: screenshot
+      payload: {fullPage: true}
+      save: "${title}.png"
+      continueOnError: true
+
+${name} in payload strings and save paths is replaced with the variable
+from vars, --var or an earlier step's "set", which stores that step's
+"result" value. Save paths are relative to --output-dir and may not leave
+it. Screenshot artifacts are written as images, other artifacts as JSON.
+Execution stops at the first failed step unless the script sets
+"stopOnError: false" or the step sets "continueOnError: true".
+
+A JSON report of every step is printed to stdout. The command fails when any
+step failed.
+
+Examples:
+  capture sessions run login.yaml --var email=me@example.com
+  capture sessions run flow.yaml --output-dir artifacts --pretty
+  capture sessions run flow.yaml --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionsRun,
+}
+
+var (
+	sessionRunVars      []string
+	sessionRunOutputDir string
+)
+
+func init() {
+	sessionsCmd.AddCommand(sessionsRunCmd)
+
+	sessionsRunCmd.Flags().StringArrayVar(&sessionRunVars, "var", nil, "Script variable as key=value, overriding the script's vars (can be repeated)")
+	sessionsRunCmd.Flags().StringVar(&sessionRunOutputDir, "output-dir", ".", "Directory that artifact save paths are relative to")
+	sessionsRunCmd.Flags().BoolVar(&sessionsPretty, "pretty", false, "Pretty print JSON output")
+}
+
+// sessionScript is the parsed form of a `sessions run` script.
+type sessionScript struct {
+	Session     scriptSessionOptions `yaml:"session"`
+	Vars        map[string]string    `yaml:"vars"`
+	StopOnError *bool                `yaml:"stopOnError"`
+	Steps       []scriptStep         `yaml:"steps"`
+}
+
+type scriptSessionOptions struct {
+	MaxTtlSeconds      int  `yaml:"maxTtlSeconds"`
+	CDP                bool `yaml:"cdp"`
+	Proxy              bool `yaml:"proxy"`
+	BypassBotDetection bool `yaml:"bypassBotDetection"`
+}
+
+type scriptStep struct {
+	Name            string                 `yaml:"name"`
+	Action          string                 `yaml:"action"`
+	Payload         map[string]interface{} `yaml:"payload"`
+	Save            string                 `yaml:"save"`
+	Set             string                 `yaml:"set"`
+	ContinueOnError bool                   `yaml:"continueOnError"`
+}
+
+// scriptReport is printed once the script has finished.
+type scriptReport struct {
+	SessionID  string       `json:"sessionId,omitempty"`
+	Success    bool         `json:"success"`
+	Steps      []stepReport `json:"steps"`
+	Closed     bool         `json:"closed"`
+	CloseError string       `json:"closeError,omitempty"`
+	DurationMs int64        `json:"durationMs"`
+}
+
+type stepReport struct {
+	Index      int                           `json:"index"`
+	Name       string                        `json:"name,omitempty"`
+	Action     string                        `json:"action"`
+	Status     string                        `json:"status"`
+	Artifact   string                        `json:"artifact,omitempty"`
+	Response   capture.SessionActionResponse `json:"response,omitempty"`
+	Error      string                        `json:"error,omitempty"`
+	DurationMs int64                         `json:"durationMs"`
+}
+
+const (
+	stepStatusOK      = "ok"
+	stepStatusFailed  = "failed"
+	stepStatusSkipped = "skipped"
+)
+
+func runSessionsRun(cmd *cobra.Command, args []string) error {
+	script, err := loadSessionScript(args[0])
+	if err != nil {
+		return err
+	}
+	overrides, err := parseScriptVars(sessionRunVars)
+	if err != nil {
+		return err
+	}
+	for key, value := range overrides {
+		script.Vars[key] = value
+	}
+
+	client := newCaptureClient()
+	if dryRun {
+		return emitJSON(previewSessionScript(client, script), sessionsPretty)
+	}
+
+	report, runErr := runSessionScript(cmd.Context(), client, script, sessionRunOutputDir)
+	if err := emitJSON(report, sessionsPretty); err != nil {
+		return err
+	}
+	return runErr
+}
+
+// parseScriptVars parses --var key=value pairs. Values are kept as typed,
+// since script variables are substituted into strings.
+func parseScriptVars(vars []string) (map[string]string, error) {
+	result := make(map[string]string, len(vars))
+	for _, v := range vars {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid var format: %s (expected key=value)", v)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+func loadSessionScript(path string) (*sessionScript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	return parseSessionScript(data)
+}
+
+func parseSessionScript(data []byte) (*sessionScript, error) {
+	var script sessionScript
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("invalid script: %w", err)
+	}
+	if len(script.Steps) == 0 {
+		return nil, fmt.Errorf("invalid script: no steps")
+	}
+	for i, step := range script.Steps {
+		if step.Action == "" {
+			return nil, fmt.Errorf("invalid script: step %d has no action", i+1)
+		}
+	}
+	if script.Vars == nil {
+		script.Vars = map[string]string{}
+	}
+	return &script, nil
+}
+
+func (s *sessionScript) createOptions() *capture.CreateSessionOptions {
+	return &capture.CreateSessionOptions{
+		MaxTtlSeconds:      s.Session.MaxTtlSeconds,
+		CDP:                s.Session.CDP,
+		Proxy:              s.Session.Proxy,
+		BypassBotDetection: s.Session.BypassBotDetection,
+	}
+}
+
+func (s *sessionScript) stopOnError(step scriptStep) bool {
+	if step.ContinueOnError {
+		return false
+	}
+	return s.StopOnError == nil || *s.StopOnError
+}
+
+// previewSessionScript lists the requests the script would send. Variables
+// set by earlier steps are unknown before running, so they are left as is.
+func previewSessionScript(client *capture.Capture, script *sessionScript) []capture.SessionRequestPreview {
+	const sessionID = "<session-id>"
+
+	previews := []capture.SessionRequestPreview{client.BuildCreateSessionRequest(script.createOptions())}
+	for _, step := range script.Steps {
+		payload, _ := expandVars(step.Payload, script.Vars, false)
+		previews = append(previews, client.BuildExecuteActionRequest(sessionID, step.Action, toPayload(payload)))
+	}
+	return append(previews, client.BuildCloseSessionRequest(sessionID))
+}
+
+// runSessionScript runs script in a new session and always closes it. The
+// returned error is the first step failure, if any.
+func runSessionScript(ctx context.Context, client *capture.Capture, script *sessionScript, outputDir string) (*scriptReport, error) {
+	start := time.Now()
+	report := &scriptReport{Steps: make([]stepReport, 0, len(script.Steps))}
+	defer func() { report.DurationMs = time.Since(start).Milliseconds() }()
+
+	session, err := client.CreateSessionTypedContext(ctx, script.createOptions())
+	if err != nil {
+		return report, fmt.Errorf("failed to create session: %w", err)
+	}
+	report.SessionID = session.ID
+	verboseLog("Created session %s", session.ID)
+	rememberSession(session, "run")
+
+	defer func() {
+		if err := closeCLISession(session, nil); err != nil {
+			report.CloseError = err.Error()
+			return
+		}
+		report.Closed = true
+	}()
+
+	vars := make(map[string]string, len(script.Vars))
+	for key, value := range script.Vars {
+		vars[key] = value
+	}
+
+	var (
+		firstErr error
+		stopped  bool
+	)
+	for i, step := range script.Steps {
+		entry := stepReport{Index: i + 1, Name: step.Name, Action: step.Action}
+		if stopped {
+			entry.Status = stepStatusSkipped
+			report.Steps = append(report.Steps, entry)
+			continue
+		}
+
+		err := runScriptStep(ctx, session, step, vars, outputDir, &entry)
+		if err != nil {
+			entry.Status = stepStatusFailed
+			entry.Error = err.Error()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("step %d (%s) failed: %w", i+1, step.Action, err)
+			}
+			if script.stopOnError(step) || ctx.Err() != nil {
+				stopped = true
+			}
+		} else {
+			entry.Status = stepStatusOK
+		}
+		verboseLog("Step %d (%s): %s", i+1, step.Action, entry.Status)
+		report.Steps = append(report.Steps, entry)
+	}
+
+	report.Success = firstErr == nil
+	return report, firstErr
+}
+
+func runScriptStep(ctx context.Context, session *capture.Session, step scriptStep, vars map[string]string, outputDir string, report *stepReport) error {
+	start := time.Now()
+	defer func() { report.DurationMs = time.Since(start).Milliseconds() }()
+
+	payload, err := expandVars(step.Payload, vars, true)
+	if err != nil {
+		return err
+	}
+
+	response, err := session.Do(ctx, step.Action, toPayload(payload))
+	if err != nil {
+		return err
+	}
+	report.Response = response
+
+	var result capture.EvaluateResult
+	if err := response.Decode(&result); err != nil {
+		return err
+	}
+	if step.Set != "" && result.Result != nil {
+		vars[step.Set] = stringifyResult(result.Result)
+	}
+
+	if step.Save != "" {
+		path, err := expandVars(step.Save, vars, true)
+		if err != nil {
+			return err
+		}
+		resolved, err := artifactPath(outputDir, path.(string))
+		if err != nil {
+			return err
+		}
+		artifact, err := saveArtifact(resolved, step.Action, response)
+		if err != nil {
+			return err
+		}
+		report.Artifact = artifact
+		if step.Action == capture.ActionScreenshot {
+			report.Response = withoutImageData(response)
+		}
+	}
+
+	if !result.Success && result.Error != "" {
+		return fmt.Errorf("%s", result.Error)
+	}
+	return nil
+}
+
+// artifactPath resolves a save path inside outputDir. Save paths may contain
+// values taken from the page, so absolute paths and paths that leave
+// outputDir are rejected.
+func artifactPath(outputDir, path string) (string, error) {
+	if filepath.IsAbs(path) || filepath.VolumeName(path) != "" {
+		return "", fmt.Errorf("save path %q must be relative to --output-dir", path)
+	}
+	base := filepath.Clean(outputDir)
+	resolved := filepath.Join(base, filepath.Clean(path))
+	rel, err := filepath.Rel(base, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("save path %q is outside --output-dir", path)
+	}
+	return resolved, nil
+}
+
+// saveArtifact writes the step response to path: screenshots as the decoded
+// image, anything else as JSON.
+func saveArtifact(path, action string, response capture.SessionActionResponse) (string, error) {
+	var data []byte
+	if action == capture.ActionScreenshot {
+		var shot capture.ScreenshotResult
+		if err := response.Decode(&shot); err != nil {
+			return "", err
+		}
+		image, err := shot.Image()
+		if err != nil {
+			return "", err
+		}
+		data = image
+	} else {
+		encoded, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal artifact: %w", err)
+		}
+		data = append(encoded, '\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write artifact: %w", err)
+	}
+	return path, nil
+}
+
+// withoutImageData drops base64 image fields that were saved as an artifact,
+// keeping the report readable.
+func withoutImageData(response capture.SessionActionResponse) capture.SessionActionResponse {
+	trimmed := make(capture.SessionActionResponse, len(response))
+	for key, value := range response {
+		switch key {
+		case "data", "screenshot", "image":
+			continue
+		}
+		trimmed[key] = value
+	}
+	return trimmed
+}
+
+func stringifyResult(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+var scriptVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+// expandVars replaces ${name} references in every string within value. In
+// strict mode an undefined variable is an error; otherwise it is kept.
+func expandVars(value interface{}, vars map[string]string, strict bool) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		var missing []string
+		expanded := scriptVarPattern.ReplaceAllStringFunc(v, func(ref string) string {
+			name := scriptVarPattern.FindStringSubmatch(ref)[1]
+			if value, ok := vars[name]; ok {
+				return value
+			}
+			missing = append(missing, name)
+			return ref
+		})
+		if strict && len(missing) > 0 {
+			sort.Strings(missing)
+			return nil, fmt.Errorf("undefined variable: %s", strings.Join(missing, ", "))
+		}
+		return expanded, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			expanded, err := expandVars(item, vars, strict)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = expanded
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			expanded, err := expandVars(item, vars, strict)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+func toPayload(value interface{}) capture.SessionActionPayload {
+	payload, _ := value.(map[string]interface{})
+	if payload == nil {
+		return capture.SessionActionPayload{}
+	}
+	return capture.SessionActionPayload(payload)
+}
@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	capture "github.com/techulus/capture-go"
+)
+
+const testScript = `
+session:
+  maxTtlSeconds: 120
+vars:
+  base: https://example.com
+steps:
+  - name: open
+    action: goto
+    payload: {url: "${base}/login"}
+  - action: evaluate
+    payload: {expression: "document.title"}
+    set: title
+  - action: screenshot
+    payload: {fullPage: true}
+    save: "shots/${title}.png"
+  - action: click
+    payload: {selector: "#missing"}
+  - action: goto
+    payload: {url: "${base}/never"}
+`
+
+func TestParseSessionScript(t *testing.T) {
+	script, err := parseSessionScript([]byte(testScript))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if script.Session.MaxTtlSeconds != 120 || len(script.Steps) != 5 || script.Vars["base"] != "https://example.com" {
+		t.Fatalf("unexpected script: %#v", script)
+	}
+	if !script.stopOnError(script.Steps[0]) {
+		t.Fatal("expected scripts to stop on error by default")
+	}
+
+	if _, err := parseSessionScript([]byte("steps:\n  - payload: {}\n")); err == nil {
+		t.Fatal("expected error for step without action")
+	}
+	if _, err := parseSessionScript([]byte("vars: {}\n")); err == nil {
+		t.Fatal("expected error for script without steps")
+	}
+}
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"base": "https://example.com", "q": "go"}
+	value := map[string]interface{}{
+		"url":  "${base}/search?q=${q}",
+		"list": []interface{}{"${q}", 3},
+	}
+
+	expanded, err := expandVars(value, vars, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := expanded.(map[string]interface{})
+	if got["url"] != "https://example.com/search?q=go" || got["list"].([]interface{})[0] != "go" {
+		t.Fatalf("unexpected expansion: %#v", got)
+	}
+
+	if _, err := expandVars("${missing}", vars, true); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("expected undefined variable error, got %v", err)
+	}
+	if kept, err := expandVars("${missing}", vars, false); err != nil || kept != "${missing}" {
+		t.Fatalf("expected lenient expansion to keep the reference, got %v, %v", kept, err)
+	}
+}
+
+func TestParseScriptVarsKeepsRawStrings(t *testing.T) {
+	vars, err := parseScriptVars([]string{"zip=01234", "pin=1.50", "query=a=b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"zip": "01234", "pin": "1.50", "query": "a=b"}
+	for key, value := range want {
+		if vars[key] != value {
+			t.Errorf("expected %s=%q, got %q", key, value, vars[key])
+		}
+	}
+
+	if _, err := parseScriptVars([]string{"novalue"}); err == nil {
+		t.Fatal("expected error for var without =")
+	}
+}
+
+func TestRunSessionScript(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		actions []string
+		closed  bool
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/sessions":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"session": map[string]interface{}{"id": "sess_1", "status": "active"}})
+		case r.Method == http.MethodDelete:
+			closed = true
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"session": map[string]interface{}{"id": "sess_1", "status": "closed"}})
+		default:
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			actions = append(actions, body["type"].(string))
+
+			switch body["type"] {
+			case "goto":
+				if url := body["payload"].(map[string]interface{})["url"]; url != "https://example.com/login" {
+					t.Errorf("unexpected goto url: %v", url)
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+			case "evaluate":
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": "Home"})
+			case "screenshot":
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": base64.StdEncoding.EncodeToString([]byte("png"))})
+			default:
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "Element not found"})
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := capture.New("key", "secret")
+	client.SessionsURL = server.URL
+
+	registry := useTempSessionRegistry(t)
+
+	script, err := parseSessionScript([]byte(testScript))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dir := t.TempDir()
+
+	report, err := runSessionScript(context.Background(), client, script, dir)
+	if err == nil || ExitCode(err) != ExitInvalidAction {
+		t.Fatalf("expected invalid action error, got %v", err)
+	}
+	if report.Success || !report.Closed || !closed {
+		t.Fatalf("expected failed run with closed session, got %#v", report)
+	}
+	if ids := registeredIDs(t, registry); len(ids) != 0 {
+		t.Fatalf("expected closed session to be forgotten, got %v", ids)
+	}
+
+	statuses := make([]string, len(report.Steps))
+	for i, step := range report.Steps {
+		statuses[i] = step.Status
+	}
+	if got := strings.Join(statuses, ","); got != "ok,ok,ok,failed,skipped" {
+		t.Fatalf("unexpected step statuses: %s", got)
+	}
+	if got := strings.Join(actions, ","); got != "goto,evaluate,screenshot,click" {
+		t.Fatalf("unexpected actions: %s", got)
+	}
+
+	artifact := filepath.Join(dir, "shots", "Home.png")
+	if report.Steps[2].Artifact != artifact {
+		t.Fatalf("unexpected artifact path: %s", report.Steps[2].Artifact)
+	}
+	if _, ok := report.Steps[2].Response["data"]; ok {
+		t.Fatal("expected saved image data to be dropped from the report")
+	}
+	data, err := os.ReadFile(artifact)
+	if err != nil || string(data) != "png" {
+		t.Fatalf("unexpected artifact: %q, %v", data, err)
+	}
+}
+
+func TestArtifactPathStaysInOutputDir(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, path := range []string{"../../.bashrc", "shots/../../escape.png", "/etc/passwd", "..", "."} {
+		if got, err := artifactPath(dir, path); err == nil {
+			t.Errorf("artifactPath(%q) = %q, expected an error", path, got)
+		}
+	}
+	for path, want := range map[string]string{
+		"shots/Home.png":      filepath.Join(dir, "shots", "Home.png"),
+		"shots/../Home.png":   filepath.Join(dir, "Home.png"),
+		"./report/step1.json": filepath.Join(dir, "report", "step1.json"),
+	} {
+		if got, err := artifactPath(dir, path); err != nil || got != want {
+			t.Errorf("artifactPath(%q) = %q, %v; want %q", path, got, err, want)
+		}
+	}
+
+	client := capture.New("key", "secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sessions" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"session": map[string]interface{}{"id": "sess_1", "status": "active"}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "result": "../../.bashrc"})
+	}))
+	defer server.Close()
+	client.SessionsURL = server.URL
+	useTempSessionRegistry(t)
+
+	script, err := parseSessionScript([]byte(`
+steps:
+  - action: evaluate
+    payload: {expression: "document.title"}
+    set: title
+  - action: evaluate
+    payload: {expression: "1"}
+    save: "${title}"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	outputDir := filepath.Join(dir, "nested", "artifacts")
+	report, err := runSessionScript(context.Background(), client, script, outputDir)
+	if err == nil || report.Steps[1].Status != "failed" {
+		t.Fatalf("expected the save step to fail, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".bashrc")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be written outside the output directory, got %v", err)
+	}
+}
@@ -0,0 +1,519 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	capture "github.com/techulus/capture-go"
+	"golang.org/x/term"
+)
+
+var sessionsShellCmd = &cobra.Command{
+	Use:   "shell [session-id]",
+	Short: "Interactively run actions in a browser session",
+	Long: `Open an interactive prompt for a browser session. A new session is created
+unless the ID of an existing one is given. The session is closed when the
+shell exits, unless --keep-open is set.
+
+Each line is an action type followed by payload options as key=value, or a
+JSON payload. Values are sent as strings, except that timeoutMs, delayMs,
+clickCount, fullPage and visibleOnly take numbers or booleans. The first bare
+argument fills the action's main field, and "> file" saves the response as
+an artifact (except for evaluate, where > is part of the expression):
+
+  goto https://example.com waitUntil=networkidle
+  click "a.more"
+  type #email text=me@example.com
+  evaluate document.querySelectorAll('a').length
+  screenshot fullPage=true > page.png
+  scroll {"y": 800}
+
+Built-in commands: help, info, history, exit. Tab completes action types and
+payload keys; up and down walk the command history. Ctrl-C cancels a running
+action; at the prompt it exits like Ctrl-D.
+
+Examples:
+  capture sessions shell
+  capture sessions shell --max-ttl-seconds 900
+  capture sessions shell sess_123 --keep-open`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSessionsShell,
+}
+
+var sessionShellKeepOpen bool
+
+func init() {
+	sessionsCmd.AddCommand(sessionsShellCmd)
+
+	sessionsShellCmd.Flags().IntVar(&sessionMaxTTLSeconds, "max-ttl-seconds", 0, "Maximum session lifetime in seconds")
+	sessionsShellCmd.Flags().BoolVar(&sessionProxy, "proxy", false, "Use the authenticated user's configured browser proxy")
+	sessionsShellCmd.Flags().BoolVar(&sessionBypassBotDetection, "bypass-bot-detection", false, "Use Capture's bot-detection bypass browser when available")
+	sessionsShellCmd.Flags().BoolVar(&sessionShellKeepOpen, "keep-open", false, "Leave the session open when the shell exits")
+}
+
+// shellActions lists the known action types with their payload keys. The
+// first key is filled from the first bare argument on the line.
+var shellActions = map[string][]string{
+	capture.ActionGoto:            {"url", "waitUntil", "timeoutMs"},
+	capture.ActionClick:           {"selector", "button", "clickCount", "timeoutMs"},
+	capture.ActionType:            {"selector", "text", "delayMs", "timeoutMs"},
+	capture.ActionWaitForSelector: {"selector", "timeoutMs", "visibleOnly"},
+	capture.ActionEvaluate:        {"expression"},
+	capture.ActionScreenshot:      {"fullPage", "selector", "type"},
+	capture.ActionGetCookies:      {},
+	capture.ActionSetCookies:      {"cookies"},
+}
+
+// shellScalarKeys are the payload keys that take numbers or booleans.
+// key=value pairs for any other key are sent as strings, so text=02134
+// keeps its leading zero.
+var shellScalarKeys = map[string]bool{
+	"clickCount":  true,
+	"delayMs":     true,
+	"fullPage":    true,
+	"timeoutMs":   true,
+	"visibleOnly": true,
+}
+
+var shellBuiltins = []string{"exit", "help", "history", "info", "quit"}
+
+func runSessionsShell(cmd *cobra.Command, args []string) error {
+	if dryRun {
+		return fmt.Errorf("sessions shell does not support --dry-run")
+	}
+
+	client := newCaptureClient()
+	ctx := cmd.Context()
+
+	var session *capture.Session
+	if len(args) == 1 {
+		session = client.AttachSession(args[0])
+		if err := session.Refresh(ctx); err != nil {
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+	} else {
+		var err error
+		session, err = client.CreateSessionTypedContext(ctx, &capture.CreateSessionOptions{
+			MaxTtlSeconds:      sessionMaxTTLSeconds,
+			Proxy:              sessionProxy,
+			BypassBotDetection: sessionBypassBotDetection,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		rememberSession(session, "shell")
+	}
+
+	if !sessionShellKeepOpen {
+		defer closeCLISession(session, os.Stderr)
+	}
+
+	fmt.Fprintf(os.Stderr, "Session %s. Type \"help\" for commands, Ctrl-D to exit.\n", session.ID)
+	sh := &sessionShell{session: session}
+	return sh.run(ctx, os.Stdin, os.Stdout)
+}
+
+// sessionShell is the state of an interactive `sessions shell`.
+type sessionShell struct {
+	session *capture.Session
+	history []string
+	out     io.Writer
+}
+
+// run reads commands until EOF or exit. A terminal gets line editing,
+// history and completion; other input is read line by line.
+func (s *sessionShell) run(ctx context.Context, in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		s.out = out
+		return s.runLines(ctx, in)
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to configure terminal: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	input := newShellInput(in)
+	terminal := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{input, out}, "capture> ")
+	terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return s.complete(line, pos)
+	}
+	if width, height, err := term.GetSize(fd); err == nil {
+		terminal.SetSize(width, height)
+	}
+	s.out = terminal
+
+	for {
+		line, err := terminal.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var exit bool
+		input.interruptible(ctx, func(ctx context.Context) {
+			exit = s.exec(ctx, line)
+		})
+		if exit || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (s *sessionShell) runLines(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if s.exec(ctx, scanner.Text()) || ctx.Err() != nil {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// exec runs one command line and reports whether it asked the shell to
+// exit.
+func (s *sessionShell) exec(ctx context.Context, line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return false
+	}
+	s.history = append(s.history, line)
+
+	name, rest, _ := strings.Cut(line, " ")
+	switch name {
+	case "exit", "quit":
+		return true
+	case "help":
+		s.printHelp()
+	case "history":
+		for i, entry := range s.history {
+			fmt.Fprintf(s.out, "%4d  %s\n", i+1, entry)
+		}
+	case "info":
+		if err := s.session.Refresh(ctx); err != nil {
+			s.printError(err)
+			return false
+		}
+		s.printJSON(s.session.Raw)
+	default:
+		s.runAction(ctx, name, strings.TrimSpace(rest))
+	}
+	return false
+}
+
+// keyCtrlC is the byte a terminal in raw mode sends for Ctrl-C.
+const keyCtrlC = 3
+
+// shellInput reads the terminal in the background for the line editor. Raw
+// mode delivers Ctrl-C as a byte instead of SIGINT, so while a command runs
+// shellInput watches for it and cancels the command instead of passing it on
+// to the prompt.
+type shellInput struct {
+	mu     sync.Mutex
+	ready  *sync.Cond
+	buf    []byte
+	err    error
+	cancel context.CancelFunc
+}
+
+func newShellInput(in io.Reader) *shellInput {
+	s := &shellInput{}
+	s.ready = sync.NewCond(&s.mu)
+	go s.pump(in)
+	return s
+}
+
+func (s *shellInput) pump(in io.Reader) {
+	chunk := make([]byte, 256)
+	for {
+		n, err := in.Read(chunk)
+		data := chunk[:n]
+
+		s.mu.Lock()
+		if s.cancel != nil && bytes.IndexByte(data, keyCtrlC) >= 0 {
+			s.cancel()
+			data = bytes.ReplaceAll(data, []byte{keyCtrlC}, nil)
+		}
+		s.buf = append(s.buf, data...)
+		if err != nil {
+			s.err = err
+		}
+		s.ready.Broadcast()
+		s.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *shellInput) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.buf) == 0 && s.err == nil {
+		s.ready.Wait()
+	}
+	if len(s.buf) == 0 {
+		return 0, s.err
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// interruptible runs fn with a context that Ctrl-C cancels.
+func (s *shellInput) interruptible(ctx context.Context, fn func(context.Context)) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.cancel = nil
+		s.mu.Unlock()
+	}()
+
+	fn(ctx)
+}
+
+func (s *sessionShell) runAction(ctx context.Context, action, args string) {
+	var artifact string
+	if action != capture.ActionEvaluate {
+		args, artifact = splitRedirect(args)
+	}
+	payload, err := parseShellPayload(action, args)
+	if err != nil {
+		s.printError(err)
+		return
+	}
+
+	response, err := s.session.Do(ctx, action, payload)
+	if err != nil {
+		s.printError(err)
+		return
+	}
+
+	if artifact != "" {
+		path, err := saveArtifact(artifact, action, response)
+		if err != nil {
+			s.printError(err)
+			return
+		}
+		fmt.Fprintf(s.out, "Saved %s\n", path)
+		if action == capture.ActionScreenshot {
+			response = withoutImageData(response)
+		}
+	} else if action == capture.ActionScreenshot {
+		response = summarizeImageData(response)
+	}
+	s.printJSON(response)
+}
+
+func (s *sessionShell) printJSON(value interface{}) {
+	encoder := json.NewEncoder(s.out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		s.printError(err)
+	}
+}
+
+func (s *sessionShell) printError(err error) {
+	fmt.Fprintf(s.out, "Error: %v\n", err)
+}
+
+func (s *sessionShell) printHelp() {
+	fmt.Fprintln(s.out, "Actions:")
+	for _, action := range sortedShellActions() {
+		fmt.Fprintf(s.out, "  %-16s %s\n", action, strings.Join(shellActions[action], " "))
+	}
+	fmt.Fprintln(s.out, "Any other action type is sent as is, e.g. scroll {\"y\": 800}.")
+	fmt.Fprintln(s.out, "Append \"> file\" to save the response; screenshots are saved as images.")
+	fmt.Fprintln(s.out, "Commands: help, info, history, exit")
+}
+
+func sortedShellActions() []string {
+	actions := make([]string, 0, len(shellActions))
+	for action := range shellActions {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// splitRedirect separates a trailing "> file" from the arguments.
+func splitRedirect(args string) (string, string) {
+	i := strings.LastIndex(args, ">")
+	if i < 0 || (i > 0 && args[i-1] != ' ') {
+		return args, ""
+	}
+	path := strings.TrimSpace(args[i+1:])
+	if path == "" || strings.ContainsAny(path, " \"'") {
+		return args, ""
+	}
+	return strings.TrimSpace(args[:i]), path
+}
+
+// parseShellPayload builds an action payload from a JSON object or from
+// shell words. Evaluate takes the rest of the line as its expression.
+func parseShellPayload(action, args string) (capture.SessionActionPayload, error) {
+	if strings.HasPrefix(args, "{") {
+		return parseActionPayload(args, nil)
+	}
+	if action == capture.ActionEvaluate && args != "" && !strings.HasPrefix(args, "expression=") {
+		return capture.SessionActionPayload{"expression": args}, nil
+	}
+
+	words, err := splitShellWords(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []string
+	payload := capture.SessionActionPayload{}
+	for _, word := range words {
+		if key, _, ok := strings.Cut(word, "="); ok && key != "" && !strings.ContainsAny(key, "#.[:/ ") {
+			pairs = append(pairs, word)
+			continue
+		}
+		keys := shellActions[action]
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("unexpected argument %q (use key=value)", word)
+		}
+		if _, ok := payload[keys[0]]; ok {
+			return nil, fmt.Errorf("unexpected argument %q (use key=value)", word)
+		}
+		payload[keys[0]] = word
+	}
+
+	var scalars []string
+	for _, pair := range pairs {
+		key, value, _ := strings.Cut(pair, "=")
+		if shellScalarKeys[key] {
+			scalars = append(scalars, pair)
+			continue
+		}
+		payload[key] = value
+	}
+	options, err := parseOptions(scalars)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range options {
+		payload[key] = value
+	}
+	return payload, nil
+}
+
+// splitShellWords splits a line on spaces, honouring single and double
+// quotes.
+func splitShellWords(line string) ([]string, error) {
+	var (
+		words   []string
+		current strings.Builder
+		quote   rune
+		inWord  bool
+	)
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+// summarizeImageData replaces base64 image fields with their size, since
+// printing them is of no use in a terminal.
+func summarizeImageData(response capture.SessionActionResponse) capture.SessionActionResponse {
+	summarized := withoutImageData(response)
+	for _, key := range []string{"data", "screenshot", "image"} {
+		if value, ok := response[key].(string); ok {
+			summarized[key] = fmt.Sprintf("<%d bytes base64, use \"> file\" to save>", len(value))
+		}
+	}
+	return summarized
+}
+
+// complete extends the word before pos to the longest common prefix of the
+// matching action types, commands or payload keys.
+func (s *sessionShell) complete(line string, pos int) (string, int, bool) {
+	head := line[:pos]
+	start := strings.LastIndexAny(head, " \t") + 1
+	prefix := head[start:]
+
+	var candidates []string
+	if start == 0 {
+		candidates = append(sortedShellActions(), shellBuiltins...)
+	} else {
+		action, _, _ := strings.Cut(head, " ")
+		for _, key := range shellActions[action] {
+			candidates = append(candidates, key+"=")
+		}
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+
+	completion := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, completion) {
+			completion = completion[:len(completion)-1]
+		}
+	}
+	if len(matches) == 1 && !strings.HasSuffix(completion, "=") {
+		completion += " "
+	}
+	if completion == prefix {
+		return "", 0, false
+	}
+	return head[:start] + completion + line[pos:], start + len(completion), true
+}
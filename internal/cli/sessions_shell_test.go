@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	capture "github.com/techulus/capture-go"
+	"github.com/techulus/capture-go/capturetest"
+)
+
+func TestParseShellPayload(t *testing.T) {
+	tests := []struct {
+		action string
+		args   string
+		want   capture.SessionActionPayload
+	}{
+		{"goto", "https://example.com/?q=1 timeoutMs=5000", capture.SessionActionPayload{"url": "https://example.com/?q=1", "timeoutMs": 5000}},
+		{"click", `"a.more > span" clickCount=2`, capture.SessionActionPayload{"selector": "a.more > span", "clickCount": 2}},
+		{"type", "#email text='me@example.com'", capture.SessionActionPayload{"selector": "#email", "text": "me@example.com"}},
+		{"evaluate", "document.title.length > 3", capture.SessionActionPayload{"expression": "document.title.length > 3"}},
+		{"scroll", `{"y": 800}`, capture.SessionActionPayload{"y": float64(800)}},
+		{"screenshot", "fullPage=true", capture.SessionActionPayload{"fullPage": true}},
+		{"type", "#zip text=02134 delayMs=50", capture.SessionActionPayload{"selector": "#zip", "text": "02134", "delayMs": 50}},
+		{"goto", "url=https://example.com waitUntil=true", capture.SessionActionPayload{"url": "https://example.com", "waitUntil": "true"}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseShellPayload(tt.action, tt.args)
+		if err != nil {
+			t.Fatalf("parseShellPayload(%q, %q) error: %v", tt.action, tt.args, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseShellPayload(%q, %q) = %#v, want %#v", tt.action, tt.args, got, tt.want)
+		}
+	}
+
+	if _, err := parseShellPayload("goto", "a b"); err == nil {
+		t.Error("expected error for a second bare argument")
+	}
+	if _, err := parseShellPayload("click", `"unterminated`); err == nil {
+		t.Error("expected error for an unterminated quote")
+	}
+}
+
+func TestSplitRedirect(t *testing.T) {
+	args, path := splitRedirect("fullPage=true > shots/page.png")
+	if args != "fullPage=true" || path != "shots/page.png" {
+		t.Fatalf("unexpected split: %q, %q", args, path)
+	}
+	if args, path := splitRedirect(`"a>b"`); args != `"a>b"` || path != "" {
+		t.Fatalf("expected > inside a word to be kept, got %q, %q", args, path)
+	}
+}
+
+func TestShellComplete(t *testing.T) {
+	sh := &sessionShell{}
+	tests := []struct {
+		line    string
+		want    string
+		wantPos int
+		ok      bool
+	}{
+		{"scr", "screenshot ", 11, true},
+		{"wa", "waitForSelector ", 16, true},
+		{"e", "e", 0, false}, // evaluate and exit share no longer prefix
+		{"screenshot full", "screenshot fullPage=", 20, true},
+		{"click #a time", "click #a timeoutMs=", 19, true},
+		{"goto x", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, pos, ok := sh.complete(tt.line, len(tt.line))
+		if ok != tt.ok || (ok && (got != tt.want || pos != tt.wantPos)) {
+			t.Errorf("complete(%q) = %q, %d, %v; want %q, %d, %v", tt.line, got, pos, ok, tt.want, tt.wantPos, tt.ok)
+		}
+	}
+}
+
+func TestSessionShellRunsLines(t *testing.T) {
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		actions = append(actions, body["type"].(string))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": "aW1n"})
+	}))
+	defer server.Close()
+
+	client := capture.New("key", "secret")
+	client.SessionsURL = server.URL
+
+	var out bytes.Buffer
+	sh := &sessionShell{session: client.AttachSession("sess_1"), out: &out}
+	input := "goto https://example.com\n\n# comment\nscreenshot\nbogus a b\nhistory\nexit\nclick a\n"
+	if err := sh.runLines(context.Background(), strings.NewReader(input)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := strings.Join(actions, ","); got != "goto,screenshot" {
+		t.Fatalf("unexpected actions: %s", got)
+	}
+	output := out.String()
+	for _, want := range []string{`"success": true`, "<4 bytes base64", "Error: unexpected argument", "   2  screenshot"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestSessionShellTypesLeadingZero(t *testing.T) {
+	server := capturetest.NewServer()
+	defer server.Close()
+
+	session, err := server.Client().CreateSessionTyped(nil)
+	if err != nil {
+		t.Fatalf("CreateSessionTyped() error: %v", err)
+	}
+
+	var out bytes.Buffer
+	sh := &sessionShell{session: session, out: &out}
+	if err := sh.runLines(context.Background(), strings.NewReader("type #zip text=02134\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output := out.String(); strings.Contains(output, "Error") || !strings.Contains(output, `"success": true`) {
+		t.Fatalf("expected the type action to succeed, got:\n%s", output)
+	}
+}
+
+func TestShellInputCancelsOnCtrlC(t *testing.T) {
+	pr, pw := io.Pipe()
+	input := newShellInput(pr)
+
+	var canceled bool
+	input.interruptible(context.Background(), func(ctx context.Context) {
+		go pw.Write([]byte("ab\x03cd"))
+		select {
+		case <-ctx.Done():
+			canceled = true
+		case <-time.After(time.Second):
+		}
+	})
+	if !canceled {
+		t.Fatal("expected Ctrl-C to cancel the running command")
+	}
+
+	go pw.Write([]byte("\x03"))
+	got := make([]byte, 0, 5)
+	buf := make([]byte, 8)
+	for len(got) < 5 {
+		n, err := input.Read(buf)
+		if err != nil {
+			t.Fatalf("Read() error: %v", err)
+		}
+		got = append(got, buf[:n]...)
+	}
+	if string(got) != "abcd\x03" {
+		t.Fatalf("expected Ctrl-C to be dropped only while a command runs, got %q", got)
+	}
+
+	pw.Close()
+	if _, err := input.Read(buf); err != io.EOF {
+		t.Fatalf("expected EOF after the input closed, got %v", err)
+	}
+}
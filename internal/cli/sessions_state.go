@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	capture "github.com/techulus/capture-go"
+)
+
+var sessionsStateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Save and restore session cookies and localStorage",
+	Long: `Export cookies and localStorage from a session to a file, and restore them
+into a session before navigating, so a login survives across runs.
+
+Files are written as JSON storage state (compatible with Playwright) or as a
+Netscape cookies.txt file, chosen with --format or from a .txt extension.
+cookies.txt files hold cookies only.
+
+Examples:
+  capture sessions state save sess_123 state.json
+  capture sessions state save sess_123 cookies.txt
+  capture sessions state load state.json
+  capture sessions state load cookies.txt --session sess_456`,
+}
+
+var sessionsStateSaveCmd = &cobra.Command{
+	Use:   "save <session-id> <file>",
+	Short: "Export a session's cookies and localStorage to a file",
+	Long: `Export a session's cookies and the localStorage of the page it has loaded.
+Navigate the session to the site first to include its localStorage.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSessionsStateSave,
+}
+
+var sessionsStateLoadCmd = &cobra.Command{
+	Use:   "load <file>",
+	Short: "Restore cookies and localStorage into a session",
+	Long: `Restore a saved state into a new session, or into an existing one with
+--session, and print the session. Restoring localStorage navigates the
+session to each saved origin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSessionsStateLoad,
+}
+
+var (
+	sessionStateFormat    string
+	sessionStateSessionID string
+)
+
+const (
+	stateFormatJSON     = "json"
+	stateFormatNetscape = "netscape"
+)
+
+func init() {
+	sessionsCmd.AddCommand(sessionsStateCmd)
+	sessionsStateCmd.AddCommand(sessionsStateSaveCmd, sessionsStateLoadCmd)
+
+	sessionsStateCmd.PersistentFlags().StringVar(&sessionStateFormat, "format", "", "File format: json or netscape (default: from the file extension)")
+
+	sessionsStateLoadCmd.Flags().StringVar(&sessionStateSessionID, "session", "", "Restore into this existing session instead of creating one")
+	sessionsStateLoadCmd.Flags().IntVar(&sessionMaxTTLSeconds, "max-ttl-seconds", 0, "Maximum lifetime of the created session in seconds")
+	sessionsStateLoadCmd.Flags().BoolVar(&sessionProxy, "proxy", false, "Use the authenticated user's configured browser proxy")
+	sessionsStateLoadCmd.Flags().BoolVar(&sessionBypassBotDetection, "bypass-bot-detection", false, "Use Capture's bot-detection bypass browser when available")
+	sessionsStateLoadCmd.Flags().BoolVar(&sessionsPretty, "pretty", false, "Pretty print JSON output")
+}
+
+func stateFileFormat(path string) (string, error) {
+	switch strings.ToLower(sessionStateFormat) {
+	case "":
+		if strings.EqualFold(filepath.Ext(path), ".txt") {
+			return stateFormatNetscape, nil
+		}
+		return stateFormatJSON, nil
+	case stateFormatJSON:
+		return stateFormatJSON, nil
+	case stateFormatNetscape, "cookies.txt":
+		return stateFormatNetscape, nil
+	}
+	return "", fmt.Errorf("invalid --format %q (expected json or netscape)", sessionStateFormat)
+}
+
+func encodeStorageState(state *capture.StorageState, format string) ([]byte, error) {
+	if format == stateFormatNetscape {
+		var buf bytes.Buffer
+		if err := capture.WriteNetscapeCookies(&buf, state.Cookies); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func decodeStorageState(data []byte, format string) (*capture.StorageState, error) {
+	if format == stateFormatNetscape {
+		cookies, err := capture.ReadNetscapeCookies(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &capture.StorageState{Cookies: cookies}, nil
+	}
+
+	var state capture.StorageState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid state file: %w", err)
+	}
+	return &state, nil
+}
+
+func runSessionsStateSave(cmd *cobra.Command, args []string) error {
+	sessionID, path := args[0], args[1]
+	format, err := stateFileFormat(path)
+	if err != nil {
+		return err
+	}
+
+	client := newCaptureClient()
+	if dryRun {
+		return emitJSON(client.BuildExecuteActionRequest(sessionID, capture.ActionGetCookies, nil), sessionsPretty)
+	}
+
+	state, err := client.AttachSession(sessionID).StorageState(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to export session state: %w", err)
+	}
+	data, err := encodeStorageState(state, format)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if format == stateFormatNetscape && len(state.Origins) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: cookies.txt does not hold localStorage; use JSON to keep it\n")
+	}
+	fmt.Fprintf(os.Stderr, "Saved %d cookies and %d origins to %s\n", len(state.Cookies), len(state.Origins), path)
+	return nil
+}
+
+func runSessionsStateLoad(cmd *cobra.Command, args []string) error {
+	format, err := stateFileFormat(args[0])
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	state, err := decodeStorageState(data, format)
+	if err != nil {
+		return err
+	}
+
+	client := newCaptureClient()
+	options := &capture.CreateSessionOptions{
+		MaxTtlSeconds:      sessionMaxTTLSeconds,
+		Proxy:              sessionProxy,
+		BypassBotDetection: sessionBypassBotDetection,
+	}
+	if dryRun {
+		sessionID := sessionStateSessionID
+		previews := []capture.SessionRequestPreview{}
+		if sessionID == "" {
+			sessionID = "<session-id>"
+			previews = append(previews, client.BuildCreateSessionRequest(options))
+		}
+		previews = append(previews, client.BuildExecuteActionRequest(sessionID, capture.ActionSetCookies, capture.SessionActionPayload{"cookies": state.Cookies}))
+		return emitJSON(previews, sessionsPretty)
+	}
+
+	ctx := cmd.Context()
+	var session *capture.Session
+	if sessionStateSessionID != "" {
+		session = client.AttachSession(sessionStateSessionID)
+	} else {
+		session, err = client.CreateSessionTypedContext(ctx, options)
+		if err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		rememberSession(session, "state load")
+	}
+
+	if err := session.RestoreStorageState(ctx, state); err != nil {
+		if sessionStateSessionID == "" {
+			// Do not leave a half-restored session running.
+			_ = closeCLISession(session, os.Stderr)
+		}
+		return fmt.Errorf("failed to restore session state: %w", err)
+	}
+
+	if err := session.Refresh(ctx); err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Restored %d cookies and %d origins into %s\n", len(state.Cookies), len(state.Origins), session.ID)
+	return emitJSON(capture.SessionResponse{"session": session.Raw}, sessionsPretty)
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func TestStateFileFormat(t *testing.T) {
+	prev := sessionStateFormat
+	defer func() { sessionStateFormat = prev }()
+
+	tests := []struct {
+		flag, path, want string
+	}{
+		{"", "state.json", stateFormatJSON},
+		{"", "cookies.TXT", stateFormatNetscape},
+		{"netscape", "state.json", stateFormatNetscape},
+		{"json", "cookies.txt", stateFormatJSON},
+	}
+	for _, tt := range tests {
+		sessionStateFormat = tt.flag
+		got, err := stateFileFormat(tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("stateFileFormat(%q) with --format %q = %q, %v; want %q", tt.path, tt.flag, got, err, tt.want)
+		}
+	}
+
+	sessionStateFormat = "yaml"
+	if _, err := stateFileFormat("state.yaml"); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
+
+func TestStorageStateEncoding(t *testing.T) {
+	state := &capture.StorageState{
+		Cookies: []capture.Cookie{{Name: "sid", Value: "abc", Domain: ".example.com", Path: "/", Expires: -1, HTTPOnly: true}},
+		Origins: []capture.OriginStorage{{Origin: "https://example.com", LocalStorage: []capture.StorageItem{{Name: "k", Value: "v"}}}},
+	}
+
+	for _, format := range []string{stateFormatJSON, stateFormatNetscape} {
+		data, err := encodeStorageState(state, format)
+		if err != nil {
+			t.Fatalf("encodeStorageState(%s) error: %v", format, err)
+		}
+		decoded, err := decodeStorageState(data, format)
+		if err != nil {
+			t.Fatalf("decodeStorageState(%s) error: %v", format, err)
+		}
+		if !reflect.DeepEqual(decoded.Cookies, state.Cookies) {
+			t.Errorf("%s cookies = %#v, want %#v", format, decoded.Cookies, state.Cookies)
+		}
+		if format == stateFormatJSON && !reflect.DeepEqual(decoded.Origins, state.Origins) {
+			t.Errorf("json origins = %#v, want %#v", decoded.Origins, state.Origins)
+		}
+	}
+}
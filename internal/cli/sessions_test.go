@@ -1,9 +1,12 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/spf13/cobra"
+	capture "github.com/techulus/capture-go"
 )
 
 func TestSessionsCreateCommandHasCDPFlag(t *testing.T) {
@@ -17,7 +20,7 @@ func TestSessionsCreateCommandHasCDPFlag(t *testing.T) {
 }
 
 func TestSessionsCommandsInheritDryRun(t *testing.T) {
-	cmds := []*cobra.Command{sessionsCreateCmd, sessionsGetCmd, sessionsCloseCmd, sessionsActionCmd}
+	cmds := []*cobra.Command{sessionsCreateCmd, sessionsGetCmd, sessionsCloseCmd, sessionsActionCmd, sessionsRunCmd}
 	for _, cmd := range cmds {
 		if cmd.InheritedFlags().Lookup("dry-run") == nil {
 			t.Fatalf("expected sessions %s to inherit --dry-run flag", cmd.Name())
@@ -49,3 +52,26 @@ func TestDryRunSkipsCredentialRequirementForSessions(t *testing.T) {
 		t.Fatal("expected non-session dry-run to still require credentials")
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("boom"), ExitError},
+		{fmt.Errorf("failed to get session: %w", &capture.SessionsAPIError{StatusCode: 404}), ExitSessionNotFound},
+		{fmt.Errorf("failed to get session: %w", &capture.SessionsAPIError{StatusCode: 410}), ExitSessionExpired},
+		{&capture.SessionsAPIError{StatusCode: 409, Body: map[string]interface{}{"code": "session_closed"}}, ExitSessionClosed},
+		{fmt.Errorf("failed to create session: %w", &capture.SessionsAPIError{StatusCode: 401}), ExitUnauthorized},
+		{fmt.Errorf("failed to execute action: %w", &capture.SessionsAPIError{StatusCode: 429}), ExitRateLimited},
+		{fmt.Errorf("failed to execute action: %w", &capture.SessionsAPIError{StatusCode: 422, ActionType: "click"}), ExitInvalidAction},
+		{fmt.Errorf("failed to capture screenshot: %w", &capture.CaptureAPIError{StatusCode: 429}), ExitRateLimited},
+	}
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	capture "github.com/techulus/capture-go"
+)
+
+var (
+	sessionsTraceJSON string
+	sessionsTraceHTML string
+
+	// sessionsTrace records the Sessions API requests of the running command
+	// when --trace-json or --trace-html is set.
+	sessionsTrace *capture.Recorder
+)
+
+func init() {
+	sessionsCmd.PersistentFlags().StringVar(&sessionsTraceJSON, "trace-json", "", "Write a JSON timeline of every Sessions API request to this file")
+	sessionsCmd.PersistentFlags().StringVar(&sessionsTraceHTML, "trace-html", "", "Write an HTML timeline report, with screenshots embedded, to this file")
+}
+
+// sessionsTraceRecorder returns the recorder shared by every client the
+// command creates, or nil when tracing is off.
+func sessionsTraceRecorder() *capture.Recorder {
+	if sessionsTraceJSON == "" && sessionsTraceHTML == "" {
+		return nil
+	}
+	if sessionsTrace == nil {
+		sessionsTrace = capture.NewRecorder()
+	}
+	return sessionsTrace
+}
+
+// writeSessionsTrace writes the recorded timeline to the trace files. It
+// runs after the command finishes, including when it fails, so a trace of
+// the failing step is kept.
+func writeSessionsTrace() error {
+	if sessionsTrace == nil {
+		return nil
+	}
+
+	write := func(path string, encode func(*bytes.Buffer) error) error {
+		if path == "" {
+			return nil
+		}
+		var buf bytes.Buffer
+		if err := encode(&buf); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+			return fmt.Errorf("failed to write trace: %w", err)
+		}
+		verboseLog("Wrote session trace to %s", path)
+		return nil
+	}
+
+	if err := write(sessionsTraceJSON, func(buf *bytes.Buffer) error { return sessionsTrace.WriteJSON(buf) }); err != nil {
+		return err
+	}
+	return write(sessionsTraceHTML, func(buf *bytes.Buffer) error { return sessionsTrace.WriteHTML(buf) })
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	capture "github.com/techulus/capture-go"
+)
+
+func TestSessionsTraceFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"session": map[string]interface{}{"id": "sess_1", "status": "active"}})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	prevJSON, prevHTML, prevTrace := sessionsTraceJSON, sessionsTraceHTML, sessionsTrace
+	defer func() { sessionsTraceJSON, sessionsTraceHTML, sessionsTrace = prevJSON, prevHTML, prevTrace }()
+
+	sessionsTraceJSON, sessionsTraceHTML, sessionsTrace = "", "", nil
+	if newCaptureClient().Recorder != nil {
+		t.Fatal("expected no recorder without trace flags")
+	}
+	if err := writeSessionsTrace(); err != nil {
+		t.Fatalf("writeSessionsTrace() without tracing: %v", err)
+	}
+
+	sessionsTraceJSON = filepath.Join(dir, "trace.json")
+	sessionsTraceHTML = filepath.Join(dir, "trace.html")
+	client := newCaptureClient()
+	client.Key, client.Secret, client.SessionsURL = "key", "secret", server.URL
+	if _, err := client.GetSessionContext(context.Background(), "sess_1"); err != nil {
+		t.Fatalf("GetSessionContext() error: %v", err)
+	}
+	if newCaptureClient().Recorder != client.Recorder {
+		t.Fatal("expected clients to share the trace recorder")
+	}
+	if err := writeSessionsTrace(); err != nil {
+		t.Fatalf("writeSessionsTrace() error: %v", err)
+	}
+
+	data, err := os.ReadFile(sessionsTraceJSON)
+	if err != nil {
+		t.Fatalf("failed to read JSON trace: %v", err)
+	}
+	var entries []capture.TimelineEntry
+	if err := json.Unmarshal(data, &entries); err != nil || len(entries) != 1 || entries[0].SessionID != "sess_1" {
+		t.Fatalf("unexpected JSON trace: %s", data)
+	}
+
+	html, err := os.ReadFile(sessionsTraceHTML)
+	if err != nil {
+		t.Fatalf("failed to read HTML trace: %v", err)
+	}
+	if !strings.Contains(string(html), "/v1/sessions/sess_1") {
+		t.Fatalf("HTML trace missing request URL:\n%s", html)
+	}
+}
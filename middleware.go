@@ -0,0 +1,91 @@
+package capture
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// APIRequest describes a request on its way to the Capture API. Render
+// requests set RequestType, TargetURL and Options; Sessions API requests
+// set Session.
+type APIRequest struct {
+	RequestType RequestType
+	TargetURL   string
+	// Options are the render options as passed by the caller. They are
+	// already encoded in HTTP.URL, so changing them has no effect.
+	Options RequestOptions
+	Session *SessionRequestPreview
+	// HTTP is the request to send. Middleware may add headers to it or
+	// replace it before calling next.
+	HTTP *http.Request
+}
+
+// APIResponse is the result of an APIRequest.
+type APIResponse struct {
+	Response *http.Response
+	// Duration is the time until the response headers arrived, including
+	// retries.
+	Duration time.Duration
+}
+
+// StatusCode returns the response status, or zero if there is no response.
+func (r *APIResponse) StatusCode() int {
+	if r == nil || r.Response == nil {
+		return 0
+	}
+	return r.Response.StatusCode
+}
+
+// RoundTrip sends an APIRequest and returns its response.
+type RoundTrip func(req *APIRequest) (*APIResponse, error)
+
+// Middleware wraps every request sent by a client. It may inspect or change
+// req, call next to continue, and inspect the response or error. Returning
+// without calling next short-circuits the request; the returned response is
+// then handled as if the API had sent it.
+type Middleware func(req *APIRequest, next RoundTrip) (*APIResponse, error)
+
+var errNoMiddlewareResponse = errors.New("middleware returned no response")
+
+// WithMiddleware adds middleware around render fetches and Sessions API
+// requests. The first middleware is the outermost. Render requests served
+// from the Cache do not reach middleware.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Capture) {
+		c.Middleware = append(c.Middleware, middleware...)
+	}
+}
+
+// send passes req through c.Middleware and then sends it with c.do.
+func (c *Capture) send(req *APIRequest, retry bool) (*http.Response, error) {
+	next := func(req *APIRequest) (*APIResponse, error) {
+		start := time.Now()
+		resp, err := c.do(req.HTTP, retry)
+		if err != nil {
+			return nil, err
+		}
+		return &APIResponse{Response: resp, Duration: time.Since(start)}, nil
+	}
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		middleware, inner := c.Middleware[i], next
+		next = func(req *APIRequest) (*APIResponse, error) {
+			return middleware(req, inner)
+		}
+	}
+
+	resp, err := next(req)
+	if err != nil {
+		if resp != nil && resp.Response != nil && resp.Response.Body != nil {
+			resp.Response.Body.Close()
+		}
+		return nil, err
+	}
+	if resp == nil || resp.Response == nil {
+		return nil, errNoMiddlewareResponse
+	}
+	if resp.Response.Body == nil {
+		resp.Response.Body = http.NoBody
+	}
+	return resp.Response, nil
+}
@@ -0,0 +1,136 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareWrapsRenderAndSessionRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace") != "abc" {
+			t.Errorf("expected injected header, got %q", r.Header.Get("X-Trace"))
+		}
+		if strings.HasPrefix(r.URL.Path, "/v1/sessions") {
+			w.Write([]byte(`{"success":true,"session":{"id":"sess_1"}}`))
+			return
+		}
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	var seen []*APIRequest
+	var statuses []int
+	c := New("key", "secret", WithMiddleware(func(req *APIRequest, next RoundTrip) (*APIResponse, error) {
+		req.HTTP.Header.Set("X-Trace", "abc")
+		seen = append(seen, req)
+		resp, err := next(req)
+		statuses = append(statuses, resp.StatusCode())
+		if err == nil && resp.Duration <= 0 {
+			t.Errorf("expected a positive duration, got %v", resp.Duration)
+		}
+		return resp, err
+	}))
+	c.APIURL, c.SessionsURL = server.URL, server.URL
+
+	options := RequestOptions{"vw": 320}
+	if _, err := c.FetchImage("https://example.com", options); err != nil {
+		t.Fatalf("FetchImage() error: %v", err)
+	}
+	if _, err := c.GetSession("sess_1"); err != nil {
+		t.Fatalf("GetSession() error: %v", err)
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(seen))
+	}
+	render, session := seen[0], seen[1]
+	if render.RequestType != RequestTypeImage || render.TargetURL != "https://example.com" || render.Options["vw"] != 320 || render.Session != nil {
+		t.Fatalf("unexpected render request: %+v", render)
+	}
+	if session.Session == nil || session.Session.Method != http.MethodGet || !strings.HasSuffix(session.Session.URL, "/v1/sessions/sess_1") || session.RequestType != "" {
+		t.Fatalf("unexpected session request: %+v", session)
+	}
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusOK {
+		t.Fatalf("unexpected statuses: %v", statuses)
+	}
+}
+
+func TestMiddlewareOrderAndShortCircuit(t *testing.T) {
+	var order []string
+	outer := func(req *APIRequest, next RoundTrip) (*APIResponse, error) {
+		order = append(order, "outer")
+		return next(req)
+	}
+	stub := func(req *APIRequest, next RoundTrip) (*APIResponse, error) {
+		order = append(order, "stub")
+		return &APIResponse{Response: &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Body:       io.NopCloser(strings.NewReader(`{"error":"slow down"}`)),
+		}}, nil
+	}
+	c := New("key", "secret", WithMiddleware(outer), WithMiddleware(stub))
+	c.APIURL = "http://127.0.0.1:0"
+
+	_, err := c.FetchContent("https://example.com", nil)
+	var apiErr *CaptureAPIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrRateLimited) || apiErr.Message != "slow down" {
+		t.Fatalf("expected the stubbed 429 as a CaptureAPIError, got %v", err)
+	}
+	if strings.Join(order, ",") != "outer,stub" {
+		t.Fatalf("unexpected middleware order: %v", order)
+	}
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	boom := errors.New("blocked")
+	c := New("key", "secret", WithMiddleware(func(req *APIRequest, next RoundTrip) (*APIResponse, error) {
+		if req.Session != nil {
+			return nil, boom
+		}
+		return nil, nil
+	}))
+
+	if _, err := c.CreateSessionContext(context.Background(), nil); !errors.Is(err, boom) {
+		t.Fatalf("expected middleware error, got %v", err)
+	}
+	if _, err := c.FetchPDF("https://example.com", nil); !errors.Is(err, errNoMiddlewareResponse) {
+		t.Fatalf("expected errNoMiddlewareResponse, got %v", err)
+	}
+}
+
+func ExampleWithMiddleware() {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+	defer api.Close()
+
+	logger := log.New(os.Stdout, "", 0)
+	c := New("key", "secret", WithMiddleware(func(req *APIRequest, next RoundTrip) (*APIResponse, error) {
+		req.HTTP.Header.Set("X-Request-ID", "req-1")
+		resp, err := next(req)
+		// Log the target rather than req.HTTP.URL, which contains the API key
+		// and the signed token for render requests.
+		target := req.TargetURL
+		if req.Session != nil {
+			target = req.Session.Method + " " + req.Session.URL
+		}
+		if err != nil {
+			logger.Printf("%s %s failed: %v", req.RequestType, target, err)
+		} else {
+			logger.Printf("%s %s: %d", req.RequestType, target, resp.StatusCode())
+		}
+		return resp, err
+	}))
+	c.APIURL, c.EdgeURL = api.URL, api.URL
+
+	_, _ = c.FetchImage("https://example.com", nil)
+	// Output:
+	// image https://example.com: 200
+}
@@ -0,0 +1,280 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ImageFormat is the encoding of a rendered screenshot.
+type ImageFormat string
+
+const (
+	ImageFormatPNG  ImageFormat = "png"
+	ImageFormatJPEG ImageFormat = "jpeg"
+	ImageFormatWebP ImageFormat = "webp"
+)
+
+// ScreenshotOptions is a typed alternative to RequestOptions for image
+// requests. Zero values are omitted, so only the fields that are set end up
+// in the signed query string. Options without a dedicated field can be passed
+// through Extra; typed fields take precedence over Extra entries with the
+// same key.
+type ScreenshotOptions struct {
+	// Viewport size in CSS pixels (vw, vh).
+	ViewportWidth  int
+	ViewportHeight int
+	// ScaleFactor is the device scale factor, e.g. 2 for retina output.
+	ScaleFactor float64
+
+	// Clip region (top, left, width, height).
+	Top    int
+	Left   int
+	Width  int
+	Height int
+
+	// Output size after rendering (resizeWidth, resizeHeight).
+	ResizeWidth  int
+	ResizeHeight int
+
+	// Format is the image encoding, sent as the "type" option.
+	Format     ImageFormat
+	BestFormat bool
+
+	FullPage    bool
+	DarkMode    bool
+	Transparent bool
+
+	// Selector and SelectorID screenshot a single element.
+	Selector   string
+	SelectorID string
+
+	// WaitFor and WaitForID delay the capture until an element appears.
+	WaitFor   string
+	WaitForID string
+	// Delay is the number of seconds to wait before capturing.
+	Delay int
+
+	BlockAds           bool
+	BlockCookieBanners bool
+	BypassBotDetection bool
+
+	UserAgent string
+	HTTPAuth  string
+	Timestamp string
+	Fresh     bool
+
+	FileName   string
+	S3Acl      string
+	S3Redirect bool
+	SkipUpload bool
+
+	Extra RequestOptions
+}
+
+// RequestOptions returns the map that buildURL signs for these options.
+func (o *ScreenshotOptions) RequestOptions() RequestOptions {
+	options := RequestOptions{}
+	if o == nil {
+		return options
+	}
+
+	for key, value := range o.Extra {
+		options[key] = value
+	}
+
+	setInt(options, "vw", o.ViewportWidth)
+	setInt(options, "vh", o.ViewportHeight)
+	setFloat(options, "scaleFactor", o.ScaleFactor)
+	setInt(options, "top", o.Top)
+	setInt(options, "left", o.Left)
+	setInt(options, "width", o.Width)
+	setInt(options, "height", o.Height)
+	setInt(options, "resizeWidth", o.ResizeWidth)
+	setInt(options, "resizeHeight", o.ResizeHeight)
+	setString(options, "type", string(o.Format))
+	setBool(options, "bestFormat", o.BestFormat)
+	setBool(options, "full", o.FullPage)
+	setBool(options, "darkMode", o.DarkMode)
+	setBool(options, "transparent", o.Transparent)
+	setString(options, "selector", o.Selector)
+	setString(options, "selectorId", o.SelectorID)
+	setString(options, "waitFor", o.WaitFor)
+	setString(options, "waitForId", o.WaitForID)
+	setInt(options, "delay", o.Delay)
+	setBool(options, "blockAds", o.BlockAds)
+	setBool(options, "blockCookieBanners", o.BlockCookieBanners)
+	setBool(options, "bypassBotDetection", o.BypassBotDetection)
+	setString(options, "userAgent", o.UserAgent)
+	setString(options, "httpAuth", o.HTTPAuth)
+	setString(options, "timestamp", o.Timestamp)
+	setBool(options, "fresh", o.Fresh)
+	setString(options, "fileName", o.FileName)
+	setString(options, "s3Acl", o.S3Acl)
+	setBool(options, "s3Redirect", o.S3Redirect)
+	setBool(options, "skipUpload", o.SkipUpload)
+
+	return options
+}
+
+func (c *Capture) BuildImageURLTyped(targetURL string, options *ScreenshotOptions) (string, error) {
+	return c.BuildImageURL(targetURL, options.RequestOptions())
+}
+
+func (c *Capture) FetchImageTyped(targetURL string, options *ScreenshotOptions) ([]byte, error) {
+	return c.FetchImageContext(context.Background(), targetURL, options.RequestOptions())
+}
+
+// FetchImageTypedContext is like FetchImageTyped but aborts the request when
+// ctx is canceled or its deadline expires.
+func (c *Capture) FetchImageTypedContext(ctx context.Context, targetURL string, options *ScreenshotOptions) ([]byte, error) {
+	return c.FetchImageContext(ctx, targetURL, options.RequestOptions())
+}
+
+// PaperFormat is a named paper size for PDF output.
+type PaperFormat string
+
+const (
+	PaperFormatLetter  PaperFormat = "Letter"
+	PaperFormatLegal   PaperFormat = "Legal"
+	PaperFormatTabloid PaperFormat = "Tabloid"
+	PaperFormatLedger  PaperFormat = "Ledger"
+	PaperFormatA0      PaperFormat = "A0"
+	PaperFormatA1      PaperFormat = "A1"
+	PaperFormatA2      PaperFormat = "A2"
+	PaperFormatA3      PaperFormat = "A3"
+	PaperFormatA4      PaperFormat = "A4"
+	PaperFormatA5      PaperFormat = "A5"
+	PaperFormatA6      PaperFormat = "A6"
+)
+
+var paperFormats = []PaperFormat{
+	PaperFormatLetter, PaperFormatLegal, PaperFormatTabloid, PaperFormatLedger,
+	PaperFormatA0, PaperFormatA1, PaperFormatA2, PaperFormatA3, PaperFormatA4, PaperFormatA5, PaperFormatA6,
+}
+
+// ParsePaperFormat returns the PaperFormat matching name, ignoring case.
+func ParsePaperFormat(name string) (PaperFormat, error) {
+	for _, format := range paperFormats {
+		if strings.EqualFold(string(format), name) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown paper format: %s", name)
+}
+
+// PDFMargins are page margins as CSS lengths, e.g. "1in", "20mm" or "10px".
+type PDFMargins struct {
+	Top    string
+	Right  string
+	Bottom string
+	Left   string
+}
+
+// UniformPDFMargins returns margins with the same length on every side.
+func UniformPDFMargins(length string) PDFMargins {
+	return PDFMargins{Top: length, Right: length, Bottom: length, Left: length}
+}
+
+// PDFOptions is a typed alternative to RequestOptions for PDF requests. Zero
+// values are omitted; Extra carries options without a dedicated field and is
+// overridden by typed fields with the same key.
+type PDFOptions struct {
+	// Format is a named paper size. Width and Height (CSS lengths such as
+	// "8.5in") define a custom paper size instead.
+	Format PaperFormat
+	Width  string
+	Height string
+
+	Margins   PDFMargins
+	Landscape bool
+	// Scale is the rendering scale of the page, between 0.1 and 2.
+	Scale float64
+	// PageRanges selects the pages to print, e.g. "1-5, 8".
+	PageRanges      string
+	PrintBackground bool
+
+	// Delay is the number of seconds to wait before rendering.
+	Delay int
+
+	UserAgent string
+	HTTPAuth  string
+	Timestamp string
+
+	FileName   string
+	S3Acl      string
+	S3Redirect bool
+
+	Extra RequestOptions
+}
+
+// RequestOptions returns the map that buildURL signs for these options.
+func (o *PDFOptions) RequestOptions() RequestOptions {
+	options := RequestOptions{}
+	if o == nil {
+		return options
+	}
+
+	for key, value := range o.Extra {
+		options[key] = value
+	}
+
+	setString(options, "format", string(o.Format))
+	setString(options, "width", o.Width)
+	setString(options, "height", o.Height)
+	setString(options, "marginTop", o.Margins.Top)
+	setString(options, "marginRight", o.Margins.Right)
+	setString(options, "marginBottom", o.Margins.Bottom)
+	setString(options, "marginLeft", o.Margins.Left)
+	setBool(options, "landscape", o.Landscape)
+	setFloat(options, "scale", o.Scale)
+	setString(options, "pageRanges", o.PageRanges)
+	setBool(options, "printBackground", o.PrintBackground)
+	setInt(options, "delay", o.Delay)
+	setString(options, "userAgent", o.UserAgent)
+	setString(options, "httpAuth", o.HTTPAuth)
+	setString(options, "timestamp", o.Timestamp)
+	setString(options, "fileName", o.FileName)
+	setString(options, "s3Acl", o.S3Acl)
+	setBool(options, "s3Redirect", o.S3Redirect)
+
+	return options
+}
+
+func (c *Capture) BuildPDFURLTyped(targetURL string, options *PDFOptions) (string, error) {
+	return c.BuildPDFURL(targetURL, options.RequestOptions())
+}
+
+func (c *Capture) FetchPDFTyped(targetURL string, options *PDFOptions) ([]byte, error) {
+	return c.FetchPDFContext(context.Background(), targetURL, options.RequestOptions())
+}
+
+// FetchPDFTypedContext is like FetchPDFTyped but aborts the request when ctx
+// is canceled or its deadline expires.
+func (c *Capture) FetchPDFTypedContext(ctx context.Context, targetURL string, options *PDFOptions) ([]byte, error) {
+	return c.FetchPDFContext(ctx, targetURL, options.RequestOptions())
+}
+
+func setInt(options RequestOptions, key string, value int) {
+	if value != 0 {
+		options[key] = value
+	}
+}
+
+func setFloat(options RequestOptions, key string, value float64) {
+	if value != 0 {
+		options[key] = value
+	}
+}
+
+func setString(options RequestOptions, key, value string) {
+	if value != "" {
+		options[key] = value
+	}
+}
+
+func setBool(options RequestOptions, key string, value bool) {
+	if value {
+		options[key] = value
+	}
+}
@@ -0,0 +1,119 @@
+package capture
+
+import (
+	"testing"
+)
+
+func TestScreenshotOptionsRequestOptions(t *testing.T) {
+	opts := &ScreenshotOptions{
+		ViewportWidth:      1920,
+		ViewportHeight:     1080,
+		ScaleFactor:        2,
+		Format:             ImageFormatWebP,
+		FullPage:           true,
+		DarkMode:           true,
+		Selector:           ".main",
+		Delay:              3,
+		BlockAds:           true,
+		BlockCookieBanners: true,
+		Extra:              RequestOptions{"type": "png", "custom": "value"},
+	}
+
+	got := opts.RequestOptions()
+	want := RequestOptions{
+		"vw":                 1920,
+		"vh":                 1080,
+		"scaleFactor":        float64(2),
+		"type":               "webp",
+		"full":               true,
+		"darkMode":           true,
+		"selector":           ".main",
+		"delay":              3,
+		"blockAds":           true,
+		"blockCookieBanners": true,
+		"custom":             "value",
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("RequestOptions() = %#v, want %#v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("RequestOptions()[%s] = %v (%T), want %v (%T)", key, got[key], got[key], value, value)
+		}
+	}
+}
+
+func TestScreenshotOptionsNil(t *testing.T) {
+	var opts *ScreenshotOptions
+	if got := opts.RequestOptions(); len(got) != 0 {
+		t.Fatalf("expected empty options, got %#v", got)
+	}
+}
+
+func TestBuildImageURLTypedMatchesMap(t *testing.T) {
+	c := New("test_key", "test_secret")
+
+	typed, err := c.BuildImageURLTyped("https://example.com", &ScreenshotOptions{
+		ViewportWidth: 1280,
+		FullPage:      true,
+		Format:        ImageFormatJPEG,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	untyped, err := c.BuildImageURL("https://example.com", RequestOptions{
+		"vw":   1280,
+		"full": true,
+		"type": "jpeg",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if typed != untyped {
+		t.Fatalf("typed URL %s differs from map URL %s", typed, untyped)
+	}
+}
+
+func TestPDFOptionsRequestOptions(t *testing.T) {
+	opts := &PDFOptions{
+		Format:          PaperFormatA4,
+		Margins:         PDFMargins{Top: "1in", Bottom: "2cm"},
+		Landscape:       true,
+		Scale:           0.8,
+		PageRanges:      "1-3",
+		PrintBackground: true,
+	}
+
+	got := opts.RequestOptions()
+	want := RequestOptions{
+		"format":          "A4",
+		"marginTop":       "1in",
+		"marginBottom":    "2cm",
+		"landscape":       true,
+		"scale":           0.8,
+		"pageRanges":      "1-3",
+		"printBackground": true,
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("RequestOptions() = %#v, want %#v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("RequestOptions()[%s] = %v (%T), want %v (%T)", key, got[key], got[key], value, value)
+		}
+	}
+}
+
+func TestParsePaperFormat(t *testing.T) {
+	format, err := ParsePaperFormat("letter")
+	if err != nil || format != PaperFormatLetter {
+		t.Fatalf("ParsePaperFormat(letter) = %q, %v", format, err)
+	}
+	if _, err := ParsePaperFormat("B5"); err == nil {
+		t.Fatal("expected error for unknown paper format")
+	}
+}
@@ -0,0 +1,347 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by SessionPool.Acquire after Close.
+var ErrPoolClosed = errors.New("session pool is closed")
+
+const (
+	defaultPoolHealthCheckInterval = 30 * time.Second
+	defaultPoolRecycleBefore       = 30 * time.Second
+)
+
+// SessionPoolOptions configures a SessionPool.
+type SessionPoolOptions struct {
+	// Size is the number of sessions the pool keeps alive and the maximum
+	// number of sessions handed out at once. Defaults to 1.
+	Size int
+	// Session is used for every session the pool creates.
+	Session *CreateSessionOptions
+	// HealthCheckInterval is how often idle sessions are verified with
+	// GetSession and refreshed in the background. A session that has not
+	// been checked within the interval is also verified before Acquire
+	// returns it. Defaults to 30s; a negative value disables health checks.
+	HealthCheckInterval time.Duration
+	// RecycleBefore replaces sessions this long before they expire, so a
+	// handed out session is never about to hit MaxTtlSeconds. Defaults to
+	// 30s.
+	RecycleBefore time.Duration
+}
+
+// SessionPool keeps warm browser sessions for workers that run many short
+// scripted tasks. Sessions are handed out with Acquire and returned with
+// Release, or Discard when they should not be reused.
+type SessionPool struct {
+	client  *Capture
+	options SessionPoolOptions
+	slots   chan struct{}
+	done    chan struct{}
+
+	mu     sync.Mutex
+	idle   []*pooledSession
+	inUse  map[*Session]*pooledSession
+	live   int
+	closed bool
+}
+
+type pooledSession struct {
+	session   *Session
+	expiresAt time.Time
+	checkedAt time.Time
+}
+
+func NewSessionPool(client *Capture, options SessionPoolOptions) *SessionPool {
+	if options.Size < 1 {
+		options.Size = 1
+	}
+	if options.HealthCheckInterval == 0 {
+		options.HealthCheckInterval = defaultPoolHealthCheckInterval
+	}
+	if options.RecycleBefore <= 0 {
+		options.RecycleBefore = defaultPoolRecycleBefore
+	}
+
+	p := &SessionPool{
+		client:  client,
+		options: options,
+		slots:   make(chan struct{}, options.Size),
+		done:    make(chan struct{}),
+		inUse:   map[*Session]*pooledSession{},
+	}
+	if options.HealthCheckInterval > 0 {
+		go p.maintain(options.HealthCheckInterval)
+	}
+	return p
+}
+
+// Warm creates sessions until the pool holds Size of them.
+func (p *SessionPool) Warm(ctx context.Context) error {
+	for {
+		if !p.reserve() {
+			return nil
+		}
+		entry, err := p.create(ctx)
+		if err != nil {
+			p.unreserve()
+			return err
+		}
+		p.putIdle(entry)
+	}
+}
+
+// Acquire returns a healthy session, waiting for one to be released when
+// Size sessions are already in use.
+func (p *SessionPool) Acquire(ctx context.Context) (*Session, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		entry, err := p.popIdle()
+		if err != nil {
+			<-p.slots
+			return nil, err
+		}
+
+		if entry == nil {
+			p.mu.Lock()
+			p.live++
+			p.mu.Unlock()
+			entry, err = p.create(ctx)
+			if err != nil {
+				p.unreserve()
+				<-p.slots
+				return nil, err
+			}
+		} else if !p.usable(ctx, entry) {
+			p.retire(entry)
+			continue
+		}
+
+		p.mu.Lock()
+		p.inUse[entry.session] = entry
+		p.mu.Unlock()
+		return entry.session, nil
+	}
+}
+
+// Release returns a session to the pool for reuse.
+func (p *SessionPool) Release(session *Session) {
+	p.mu.Lock()
+	entry, ok := p.inUse[session]
+	delete(p.inUse, session)
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if session.closed {
+		p.mu.Lock()
+		p.live--
+		p.mu.Unlock()
+	} else {
+		p.putIdle(entry)
+	}
+	<-p.slots
+}
+
+// Discard closes a session instead of returning it to the pool, e.g. after
+// a task left the browser in an unknown state.
+func (p *SessionPool) Discard(session *Session) {
+	p.mu.Lock()
+	entry, ok := p.inUse[session]
+	delete(p.inUse, session)
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	p.retire(entry)
+	<-p.slots
+}
+
+// Close closes every idle session and stops background maintenance.
+// Sessions still in use are closed when they are released.
+func (p *SessionPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+	close(p.done)
+
+	var firstErr error
+	for _, entry := range idle {
+		if err := p.retire(entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// reserve counts a session about to be created, unless the pool is full.
+func (p *SessionPool) reserve() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || p.live >= p.options.Size {
+		return false
+	}
+	p.live++
+	return true
+}
+
+func (p *SessionPool) unreserve() {
+	p.mu.Lock()
+	p.live--
+	p.mu.Unlock()
+}
+
+func (p *SessionPool) create(ctx context.Context) (*pooledSession, error) {
+	session, err := p.client.CreateSessionTypedContext(ctx, p.options.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := &pooledSession{session: session, expiresAt: session.ExpiresAt, checkedAt: now}
+	if entry.expiresAt.IsZero() && p.options.Session != nil && p.options.Session.MaxTtlSeconds > 0 {
+		entry.expiresAt = now.Add(time.Duration(p.options.Session.MaxTtlSeconds) * time.Second)
+	}
+	return entry, nil
+}
+
+func (p *SessionPool) popIdle() (*pooledSession, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+	if len(p.idle) == 0 {
+		return nil, nil
+	}
+	entry := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+	return entry, nil
+}
+
+// putIdle parks a session for reuse, closing it instead when the pool is
+// closed or already holds Size sessions.
+func (p *SessionPool) putIdle(entry *pooledSession) {
+	p.mu.Lock()
+	if p.closed || p.live > p.options.Size {
+		p.mu.Unlock()
+		p.retire(entry)
+		return
+	}
+	p.idle = append(p.idle, entry)
+	p.mu.Unlock()
+}
+
+// takeIdle removes entry from the idle list, reporting whether it was there.
+func (p *SessionPool) takeIdle(entry *pooledSession) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, candidate := range p.idle {
+		if candidate == entry {
+			p.idle = append(p.idle[:i], p.idle[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *SessionPool) expiring(entry *pooledSession, now time.Time) bool {
+	return !entry.expiresAt.IsZero() && now.Add(p.options.RecycleBefore).After(entry.expiresAt)
+}
+
+// usable reports whether an idle session can be handed out, verifying it
+// with the API when its last health check is older than the interval.
+func (p *SessionPool) usable(ctx context.Context, entry *pooledSession) bool {
+	now := time.Now()
+	if p.expiring(entry, now) {
+		return false
+	}
+	if p.options.HealthCheckInterval < 0 || now.Sub(entry.checkedAt) < p.options.HealthCheckInterval {
+		return true
+	}
+	return p.check(ctx, entry)
+}
+
+func (p *SessionPool) check(ctx context.Context, entry *pooledSession) bool {
+	if err := entry.session.Refresh(ctx); err != nil {
+		return false
+	}
+	entry.checkedAt = time.Now()
+	if !entry.session.ExpiresAt.IsZero() {
+		entry.expiresAt = entry.session.ExpiresAt
+	}
+	return entry.session.Status == "" || entry.session.Status == "active"
+}
+
+// retire closes a session owned by the pool and forgets it.
+func (p *SessionPool) retire(entry *pooledSession) error {
+	p.mu.Lock()
+	p.live--
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return entry.session.CloseContext(ctx)
+}
+
+// maintain periodically replaces idle sessions that are about to expire or
+// fail their health check, so Acquire rarely pays for session creation.
+func (p *SessionPool) maintain(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		snapshot := append([]*pooledSession(nil), p.idle...)
+		p.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		replaced := 0
+		for _, entry := range snapshot {
+			// Take the session out of the idle list while it is checked so
+			// Acquire cannot hand it out concurrently.
+			if !p.takeIdle(entry) {
+				continue
+			}
+			if !p.expiring(entry, time.Now()) && p.check(ctx, entry) {
+				p.putIdle(entry)
+				continue
+			}
+			p.retire(entry)
+			replaced++
+		}
+		for ; replaced > 0 && p.reserve(); replaced-- {
+			entry, err := p.create(ctx)
+			if err != nil {
+				p.unreserve()
+				break
+			}
+			p.putIdle(entry)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,220 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSessionsAPI is a minimal in-memory Sessions API for pool tests.
+type fakeSessionsAPI struct {
+	mu       sync.Mutex
+	next     int
+	ttl      time.Duration
+	sessions map[string]string
+	created  int
+	closed   int
+	gets     int
+}
+
+func newFakeSessionsAPI(ttl time.Duration) *fakeSessionsAPI {
+	return &fakeSessionsAPI{ttl: ttl, sessions: map[string]string{}}
+}
+
+func (f *fakeSessionsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := strings.TrimPrefix(r.URL.Path, "/v1/sessions/")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/sessions":
+		f.next++
+		f.created++
+		id = fmt.Sprintf("sess_%d", f.next)
+		f.sessions[id] = "active"
+	case r.Method == http.MethodGet:
+		f.gets++
+	case r.Method == http.MethodDelete:
+		f.closed++
+		f.sessions[id] = "closed"
+	}
+
+	status, ok := f.sessions[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "Session not found"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"session": map[string]interface{}{
+			"id":        id,
+			"status":    status,
+			"expiresAt": time.Now().Add(f.ttl).UTC().Format(time.RFC3339Nano),
+		},
+	})
+}
+
+func (f *fakeSessionsAPI) counts() (created, closed, gets int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.created, f.closed, f.gets
+}
+
+func newPoolTestClient(t *testing.T, api *fakeSessionsAPI) *Capture {
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	c := New("user_123", "secret")
+	c.SessionsURL = server.URL
+	return c
+}
+
+func TestSessionPoolReusesSessions(t *testing.T) {
+	api := newFakeSessionsAPI(time.Hour)
+	pool := NewSessionPool(newPoolTestClient(t, api), SessionPoolOptions{Size: 2, HealthCheckInterval: -1})
+	ctx := context.Background()
+
+	if err := pool.Warm(ctx); err != nil {
+		t.Fatalf("Warm() error: %v", err)
+	}
+	if created, _, _ := api.counts(); created != 2 {
+		t.Fatalf("expected 2 warm sessions, got %d", created)
+	}
+
+	first, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+	second, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("expected distinct sessions, got %s twice", first.ID)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Acquire to block while the pool is exhausted, got %v", err)
+	}
+
+	pool.Release(first)
+	again, err := pool.Acquire(ctx)
+	if err != nil || again.ID != first.ID {
+		t.Fatalf("expected released session to be reused, got %v, %v", again, err)
+	}
+	pool.Release(again)
+	pool.Discard(second)
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if created, closed, _ := api.counts(); created != 2 || closed != 2 {
+		t.Fatalf("created %d and closed %d sessions, want 2 and 2", created, closed)
+	}
+	if _, err := pool.Acquire(ctx); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func TestSessionPoolCloseWakesWaiters(t *testing.T) {
+	api := newFakeSessionsAPI(time.Hour)
+	pool := NewSessionPool(newPoolTestClient(t, api), SessionPoolOptions{Size: 1, HealthCheckInterval: -1})
+	ctx := context.Background()
+
+	session, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+
+	waitErr := make(chan error, 1)
+	go func() {
+		_, err := pool.Acquire(ctx)
+		waitErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	select {
+	case err := <-waitErr:
+		if !errors.Is(err, ErrPoolClosed) {
+			t.Fatalf("expected ErrPoolClosed for the waiting caller, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire still blocked after Close")
+	}
+	pool.Release(session)
+}
+
+func TestSessionPoolRecyclesExpiringSessions(t *testing.T) {
+	api := newFakeSessionsAPI(10 * time.Second)
+	pool := NewSessionPool(newPoolTestClient(t, api), SessionPoolOptions{
+		Size:                1,
+		HealthCheckInterval: -1,
+		RecycleBefore:       time.Minute,
+	})
+	defer pool.Close()
+	ctx := context.Background()
+
+	first, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+	pool.Release(first)
+
+	second, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Fatal("expected session close to expiry to be recycled")
+	}
+	if _, closed, _ := api.counts(); closed != 1 {
+		t.Fatalf("expected recycled session to be closed, got %d closes", closed)
+	}
+	pool.Release(second)
+}
+
+func TestSessionPoolHealthCheck(t *testing.T) {
+	api := newFakeSessionsAPI(time.Hour)
+	pool := NewSessionPool(newPoolTestClient(t, api), SessionPoolOptions{Size: 1, HealthCheckInterval: time.Hour})
+	defer pool.Close()
+	ctx := context.Background()
+
+	first, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+	pool.Release(first)
+
+	// Simulate the API closing the session behind the pool's back and the
+	// last health check being stale.
+	api.mu.Lock()
+	api.sessions[first.ID] = "closed"
+	api.mu.Unlock()
+	pool.mu.Lock()
+	pool.idle[0].checkedAt = time.Now().Add(-2 * time.Hour)
+	pool.mu.Unlock()
+
+	second, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Fatal("expected unhealthy session to be replaced")
+	}
+	if _, _, gets := api.counts(); gets != 1 {
+		t.Fatalf("expected 1 health check, got %d", gets)
+	}
+	pool.Release(second)
+}
@@ -0,0 +1,270 @@
+package capture
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimelineEntry records one Sessions API request made through a client with
+// a Recorder.
+type TimelineEntry struct {
+	Index      int                    `json:"index"`
+	SessionID  string                 `json:"sessionId,omitempty"`
+	ActionType string                 `json:"actionType,omitempty"`
+	Request    SessionRequestPreview  `json:"request"`
+	StatusCode int                    `json:"statusCode,omitempty"`
+	Response   map[string]interface{} `json:"response,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	StartedAt  time.Time              `json:"startedAt"`
+	// Duration is the time until the response was read, in nanoseconds
+	// when encoded as JSON.
+	Duration time.Duration `json:"duration"`
+}
+
+// Recorder keeps a timeline of Sessions API requests, for debugging
+// multi-step session flows. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []TimelineEntry
+}
+
+// NewRecorder returns an empty Recorder. Pass it to New with WithRecorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// WithRecorder records every Sessions API request, including its response,
+// status and duration, into r.
+func WithRecorder(r *Recorder) Option {
+	return func(c *Capture) {
+		c.Recorder = r
+	}
+}
+
+func (r *Recorder) record(entry TimelineEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.Index = len(r.entries) + 1
+	r.entries = append(r.entries, entry)
+}
+
+// Entries returns the recorded requests in the order they were made.
+func (r *Recorder) Entries() []TimelineEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]TimelineEntry(nil), r.entries...)
+}
+
+// Reset discards the recorded requests.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// newTimelineEntry describes a finished Sessions API request. statusCode is
+// zero and respBody nil when no response was received.
+func newTimelineEntry(preview SessionRequestPreview, start time.Time, elapsed time.Duration, statusCode int, respBody []byte, err error) TimelineEntry {
+	entry := TimelineEntry{
+		Request:    preview,
+		StatusCode: statusCode,
+		StartedAt:  start,
+		Duration:   elapsed,
+	}
+	if parsed, parseErr := url.Parse(preview.URL); parseErr == nil {
+		if _, rest, ok := strings.Cut(parsed.Path, "/v1/sessions/"); ok {
+			entry.SessionID, _, _ = strings.Cut(rest, "/")
+		}
+	}
+	if body, ok := preview.Body.(map[string]interface{}); ok {
+		entry.ActionType, _ = body["type"].(string)
+	}
+	if len(respBody) > 0 {
+		response := map[string]interface{}{}
+		if json.Unmarshal(respBody, &response) != nil {
+			response = map[string]interface{}{"body": string(respBody)}
+		}
+		entry.Response = response
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// WriteJSON writes the timeline as an indented JSON array.
+func (r *Recorder) WriteJSON(w io.Writer) error {
+	entries := r.Entries()
+	if entries == nil {
+		entries = []TimelineEntry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entries); err != nil {
+		return fmt.Errorf("failed to encode timeline: %w", err)
+	}
+	return nil
+}
+
+// timelineImageKeys are response fields that carry base64 screenshots.
+var timelineImageKeys = []string{"data", "screenshot", "image"}
+
+type timelineRow struct {
+	TimelineEntry
+	Offset   time.Duration
+	BarLeft  float64
+	BarWidth float64
+	Request  string
+	Response string
+	Image    template.URL
+	Failed   bool
+}
+
+// WriteHTML writes the timeline as a self-contained HTML page, with
+// screenshots returned by the API embedded inline.
+func (r *Recorder) WriteHTML(w io.Writer) error {
+	entries := r.Entries()
+
+	var start, end time.Time
+	for i, entry := range entries {
+		if i == 0 || entry.StartedAt.Before(start) {
+			start = entry.StartedAt
+		}
+		if finished := entry.StartedAt.Add(entry.Duration); finished.After(end) {
+			end = finished
+		}
+	}
+	total := end.Sub(start)
+
+	rows := make([]timelineRow, 0, len(entries))
+	for _, entry := range entries {
+		row := timelineRow{
+			TimelineEntry: entry,
+			Offset:        entry.StartedAt.Sub(start),
+			Failed:        entry.Error != "" || entry.StatusCode >= 400,
+		}
+		if total > 0 {
+			row.BarLeft = 100 * float64(row.Offset) / float64(total)
+			row.BarWidth = 100 * float64(entry.Duration) / float64(total)
+		}
+		if row.BarWidth < 0.5 {
+			row.BarWidth = 0.5
+		}
+
+		response := entry.Response
+		if image, ok := timelineImage(entry.Response); ok {
+			row.Image = image
+			response = make(map[string]interface{}, len(entry.Response))
+			for key, value := range entry.Response {
+				response[key] = value
+			}
+			for _, key := range timelineImageKeys {
+				if value, ok := response[key].(string); ok {
+					response[key] = fmt.Sprintf("<%d bytes base64, shown below>", len(value))
+				}
+			}
+		}
+		row.Request = indentJSON(entry.Request)
+		if response != nil {
+			row.Response = indentJSON(response)
+		}
+		rows = append(rows, row)
+	}
+
+	return timelineTemplate.Execute(w, map[string]interface{}{
+		"Rows":     rows,
+		"Start":    start,
+		"Total":    total,
+		"Requests": len(rows),
+	})
+}
+
+// timelineImage returns a data URL for a screenshot in response, if any.
+func timelineImage(response map[string]interface{}) (template.URL, bool) {
+	for _, key := range timelineImageKeys {
+		value, ok := response[key].(string)
+		if !ok || value == "" {
+			continue
+		}
+		if _, err := base64.StdEncoding.DecodeString(value); err != nil {
+			continue
+		}
+		contentType, _ := response["contentType"].(string)
+		if !strings.HasPrefix(contentType, "image/") {
+			contentType = "image/png"
+		}
+		return template.URL("data:" + contentType + ";base64," + value), true
+	}
+	return "", false
+}
+
+func indentJSON(value interface{}) string {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+var timelineTemplate = template.Must(template.New("timeline").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) string {
+		return fmt.Sprintf("%.0f ms", float64(d)/float64(time.Millisecond))
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Capture session timeline</title>
+<style>
+body { font: 14px/1.4 -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; margin: 2em; color: #1f2328; }
+h1 { font-size: 1.4em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #d0d7de; vertical-align: top; }
+th { background: #f6f8fa; }
+tr.failed td { background: #fff1f0; }
+.bar { position: relative; height: 10px; background: #f6f8fa; min-width: 200px; }
+.bar span { position: absolute; top: 0; height: 10px; background: #0969da; }
+tr.failed .bar span { background: #cf222e; }
+pre { background: #f6f8fa; padding: 8px; overflow-x: auto; max-height: 400px; }
+img { max-width: 100%; border: 1px solid #d0d7de; }
+.error { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Capture session timeline</h1>
+<p>{{.Requests}} requests{{if .Requests}}, started {{.Start.Format "2006-01-02 15:04:05 MST"}}, {{ms .Total}} total{{end}}.</p>
+<table>
+<tr><th>#</th><th>Start</th><th>Request</th><th>Action</th><th>Status</th><th>Duration</th><th>Timeline</th></tr>
+{{range .Rows}}
+<tr{{if .Failed}} class="failed"{{end}}>
+<td>{{.Index}}</td>
+<td>+{{ms .Offset}}</td>
+<td>{{.TimelineEntry.Request.Method}} {{.TimelineEntry.Request.URL}}</td>
+<td>{{.ActionType}}</td>
+<td>{{if .StatusCode}}{{.StatusCode}}{{else}}-{{end}}</td>
+<td>{{ms .Duration}}</td>
+<td><div class="bar"><span style="left: {{printf "%.2f" .BarLeft}}%; width: {{printf "%.2f" .BarWidth}}%"></span></div></td>
+</tr>
+<tr{{if .Failed}} class="failed"{{end}}>
+<td></td>
+<td colspan="6">
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<details><summary>Request and response</summary>
+<pre>{{.Request}}</pre>
+{{if .Response}}<pre>{{.Response}}</pre>{{end}}
+</details>
+{{if .Image}}<img src="{{.Image}}" alt="Screenshot from request {{.Index}}">{{end}}
+</td>
+</tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
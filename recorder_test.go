@@ -0,0 +1,118 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRecordsSessionRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch body["type"] {
+		case ActionScreenshot:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": "iVBORw0KGgo=", "contentType": "image/png"})
+		case ActionClick:
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": "selector not found"})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		}
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	c := New("key", "secret", WithRecorder(recorder))
+	c.SessionsURL = server.URL
+	session := c.AttachSession("sess_1")
+	ctx := context.Background()
+
+	if _, err := session.Goto(ctx, GotoAction{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Goto() error: %v", err)
+	}
+	if _, err := session.Screenshot(ctx, ScreenshotAction{}); err != nil {
+		t.Fatalf("Screenshot() error: %v", err)
+	}
+	var apiErr *SessionsAPIError
+	if _, err := session.Click(ctx, ClickAction{Selector: "#missing"}); !errors.As(err, &apiErr) {
+		t.Fatalf("expected SessionsAPIError, got %v", err)
+	}
+
+	entries := recorder.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, want := range []string{ActionGoto, ActionScreenshot, ActionClick} {
+		entry := entries[i]
+		if entry.Index != i+1 || entry.ActionType != want || entry.SessionID != "sess_1" {
+			t.Errorf("entry %d = %d %s %s", i, entry.Index, entry.ActionType, entry.SessionID)
+		}
+		if entry.StartedAt.IsZero() || entry.Duration <= 0 {
+			t.Errorf("entry %d has no timing: %v %v", i, entry.StartedAt, entry.Duration)
+		}
+	}
+	if entries[0].StatusCode != http.StatusOK || entries[0].Error != "" {
+		t.Errorf("unexpected goto entry: %+v", entries[0])
+	}
+	if entries[2].StatusCode != http.StatusBadRequest || entries[2].Error == "" || entries[2].Response["error"] != "selector not found" {
+		t.Errorf("unexpected click entry: %+v", entries[2])
+	}
+
+	var buf bytes.Buffer
+	if err := recorder.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error: %v", err)
+	}
+	var decoded []TimelineEntry
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 3 {
+		t.Fatalf("WriteJSON() produced %d entries, %v", len(decoded), err)
+	}
+
+	buf.Reset()
+	if err := recorder.WriteHTML(&buf); err != nil {
+		t.Fatalf("WriteHTML() error: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{`src="data:image/png;base64,iVBORw0KGgo="`, "selector not found", "3 requests"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML report missing %q", want)
+		}
+	}
+
+	recorder.Reset()
+	if len(recorder.Entries()) != 0 {
+		t.Fatal("expected Reset to clear entries")
+	}
+}
+
+func TestRecorderRecordsTransportErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	recorder := NewRecorder()
+	c := New("key", "secret", WithRecorder(recorder))
+	c.SessionsURL = server.URL
+	if _, err := c.GetSessionContext(context.Background(), "sess_1"); err == nil {
+		t.Fatal("expected error from a closed server")
+	}
+
+	entries := recorder.Entries()
+	if len(entries) != 1 || entries[0].StatusCode != 0 || entries[0].Error == "" || entries[0].Request.Method != http.MethodGet {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestTimelineImageRejectsInvalidData(t *testing.T) {
+	if _, ok := timelineImage(map[string]interface{}{"data": `"><script>alert(1)</script>`}); ok {
+		t.Fatal("expected non-base64 data to be ignored")
+	}
+	image, ok := timelineImage(map[string]interface{}{"screenshot": "AAAA", "contentType": "text/html"})
+	if !ok || image != "data:image/png;base64,AAAA" {
+		t.Fatalf("timelineImage() = %q, %v", image, ok)
+	}
+}
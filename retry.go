@@ -0,0 +1,169 @@
+package capture
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how transient failures are retried. Render requests
+// are idempotent GETs and are always eligible; session requests are only
+// retried when RetrySessions is set.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. It doubles on every
+	// following attempt. Defaults to 500ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps every wait, including delays requested through
+	// Retry-After. Defaults to 30s.
+	MaxBackoff time.Duration
+	// Jitter randomly shortens each backoff by up to this fraction (0 to 1)
+	// so concurrent clients do not retry in lockstep.
+	Jitter float64
+	// RetrySessions enables retries for Sessions API calls. Creating a
+	// session or executing an action is not idempotent, so a retried call
+	// may create an extra session or repeat an action.
+	RetrySessions bool
+	// OnRetry, if set, is called before each retry.
+	OnRetry func(RetryAttempt)
+}
+
+// RetryAttempt describes a failed attempt that is about to be retried.
+type RetryAttempt struct {
+	Method string
+	URL    string
+	// Attempt is the 1-based number of the attempt that failed.
+	Attempt int
+	// StatusCode is the response status, or zero if the request failed
+	// before a response was received.
+	StatusCode int
+	Err        error
+	Wait       time.Duration
+}
+
+const (
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Capture) {
+		c.RetryPolicy = &policy
+	}
+}
+
+func (p *RetryPolicy) enabled() bool {
+	return p != nil && p.MaxAttempts > 1
+}
+
+func (p *RetryPolicy) retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before retrying after the given failed attempt.
+func (p *RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	wait := initial
+	for i := 1; i < attempt && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		wait -= time.Duration(rand.Float64() * jitter * float64(wait))
+	}
+	if retryAfter > wait {
+		wait = retryAfter
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// do sends req, retrying transient failures according to c.RetryPolicy when
+// retry is true. Request bodies are replayed through req.GetBody.
+func (c *Capture) do(req *http.Request, retry bool) (*http.Response, error) {
+	policy := c.RetryPolicy
+	if !retry || !policy.enabled() {
+		return c.Client.Do(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(ctx)
+			attemptReq.Body = body
+		}
+
+		resp, err := c.Client.Do(attemptReq)
+		if attempt >= policy.MaxAttempts || !policy.retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		var (
+			statusCode int
+			retryAfter time.Duration
+		)
+		if resp != nil {
+			statusCode = resp.StatusCode
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+			resp.Body.Close()
+		}
+
+		wait := policy.backoff(attempt, retryAfter)
+		if policy.OnRetry != nil {
+			policy.OnRetry(RetryAttempt{
+				Method:     req.Method,
+				URL:        req.URL.String(),
+				Attempt:    attempt,
+				StatusCode: statusCode,
+				Err:        err,
+				Wait:       wait,
+			})
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
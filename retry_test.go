@@ -0,0 +1,154 @@
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyRetriesTransientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte("image"))
+		}
+	}))
+	defer server.Close()
+
+	var attempts []RetryAttempt
+	c := New("test_key", "test_secret", WithRetryPolicy(RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		OnRetry: func(attempt RetryAttempt) {
+			attempts = append(attempts, attempt)
+		},
+	}))
+	c.APIURL = server.URL
+
+	data, err := c.FetchImage("https://example.com", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "image" {
+		t.Fatalf("unexpected body: %q", data)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 retries, got %d", len(attempts))
+	}
+	if attempts[0].Attempt != 1 || attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected first attempt: %#v", attempts[0])
+	}
+	if attempts[1].Attempt != 2 || attempts[1].StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("unexpected second attempt: %#v", attempts[1])
+	}
+}
+
+func TestRetryPolicyGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret", WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	c.APIURL = server.URL
+
+	_, err := c.FetchPDF("https://example.com", nil)
+	var apiErr *CaptureAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502 CaptureAPIError, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestRetryPolicyDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret", WithRetryPolicy(RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond}))
+	c.APIURL = server.URL
+
+	if _, err := c.FetchImage("https://example.com", nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestRetryPolicySessionsAreOptIn(t *testing.T) {
+	var calls int32
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+	}))
+	defer server.Close()
+
+	c := New("user_123", "secret", WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	c.SessionsURL = server.URL
+	if _, err := c.CreateSession(&CreateSessionOptions{MaxTtlSeconds: 60}); err == nil {
+		t.Fatal("expected session request not to be retried by default")
+	}
+
+	c.RetryPolicy.RetrySessions = true
+	if _, err := c.CreateSession(&CreateSessionOptions{MaxTtlSeconds: 60}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+	if len(bodies) != 2 || bodies[1]["maxTtlSeconds"] != float64(60) {
+		t.Fatalf("expected request body to be replayed, got %#v", bodies)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	if got := policy.backoff(1, 0); got != 100*time.Millisecond {
+		t.Errorf("backoff(1) = %v", got)
+	}
+	if got := policy.backoff(3, 0); got != 400*time.Millisecond {
+		t.Errorf("backoff(3) = %v", got)
+	}
+	if got := policy.backoff(10, 0); got != time.Second {
+		t.Errorf("backoff(10) = %v, want cap of 1s", got)
+	}
+	if got := policy.backoff(1, 500*time.Millisecond); got != 500*time.Millisecond {
+		t.Errorf("backoff with Retry-After = %v, want 500ms", got)
+	}
+	if got := policy.backoff(1, time.Minute); got != time.Second {
+		t.Errorf("backoff with long Retry-After = %v, want cap of 1s", got)
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		if got := policy.backoff(2, 0); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("jittered backoff(2) = %v, want between 100ms and 200ms", got)
+		}
+	}
+}
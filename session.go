@@ -0,0 +1,439 @@
+package capture
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Session is the typed form of a browser session returned by the Sessions
+// API. Fields the API adds later are still available through Raw.
+type Session struct {
+	ID                 string                 `json:"id"`
+	Status             string                 `json:"status"`
+	ConnectURL         string                 `json:"connectUrl,omitempty"`
+	CDP                bool                   `json:"cdp,omitempty"`
+	Proxy              bool                   `json:"proxy,omitempty"`
+	BypassBotDetection bool                   `json:"bypassBotDetection,omitempty"`
+	MaxTtlSeconds      int                    `json:"maxTtlSeconds,omitempty"`
+	CreatedAt          time.Time              `json:"createdAt"`
+	StartedAt          time.Time              `json:"startedAt"`
+	ExpiresAt          time.Time              `json:"expiresAt"`
+	ActionCount        int                    `json:"actionCount,omitempty"`
+	ActionSuccessCount int                    `json:"actionSuccessCount,omitempty"`
+	ActionErrorCount   int                    `json:"actionErrorCount,omitempty"`
+	Options            map[string]interface{} `json:"options,omitempty"`
+
+	// Raw is the untyped session object as returned by the API.
+	Raw map[string]interface{} `json:"-"`
+
+	client sessionClient
+	closed bool
+}
+
+// sessionClient is the subset of the client a Session handle drives.
+type sessionClient interface {
+	GetSessionContext(ctx context.Context, sessionID string) (SessionResponse, error)
+	CloseSessionContext(ctx context.Context, sessionID string) (SessionResponse, error)
+	ExecuteActionContext(ctx context.Context, sessionID, actionType string, payload SessionActionPayload) (SessionActionResponse, error)
+}
+
+// Session decodes the session object embedded in a response. Responses that
+// carry the session fields at the top level are accepted as well.
+func (r SessionResponse) Session() (*Session, error) {
+	raw, ok := r["session"].(map[string]interface{})
+	if !ok {
+		if _, hasID := r["id"]; !hasID {
+			return nil, fmt.Errorf("session response does not contain a session")
+		}
+		raw = r
+	}
+
+	var session Session
+	if err := remarshal(raw, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	session.Raw = raw
+	return &session, nil
+}
+
+// ActionResult is the typed form of a session action response.
+type ActionResult struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Title   string `json:"title,omitempty"`
+
+	// Raw is the untyped action response as returned by the API.
+	Raw SessionActionResponse `json:"-"`
+}
+
+// EvaluateResult is the response of an "evaluate" action.
+type EvaluateResult struct {
+	ActionResult
+	Result interface{} `json:"result"`
+}
+
+// ScreenshotResult is the response of a "screenshot" action.
+type ScreenshotResult struct {
+	ActionResult
+	// Data is the base64 encoded image.
+	Data        string `json:"data"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// Image decodes the screenshot bytes.
+func (r *ScreenshotResult) Image() ([]byte, error) {
+	data := r.Data
+	if data == "" {
+		for _, key := range []string{"screenshot", "image"} {
+			if value, ok := r.Raw[key].(string); ok && value != "" {
+				data = value
+				break
+			}
+		}
+	}
+	if data == "" {
+		return nil, fmt.Errorf("screenshot response does not contain image data")
+	}
+
+	image, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	return image, nil
+}
+
+// Decode unmarshals the action response into out, which is typically one of
+// the *Result types or a caller-defined struct. Result types embedding
+// ActionResult also receive the raw response.
+func (r SessionActionResponse) Decode(out interface{}) error {
+	if err := remarshal(r, out); err != nil {
+		return fmt.Errorf("failed to decode action response: %w", err)
+	}
+
+	switch v := out.(type) {
+	case *ActionResult:
+		v.Raw = r
+	case *EvaluateResult:
+		v.Raw = r
+	case *ScreenshotResult:
+		v.Raw = r
+	}
+	return nil
+}
+
+func (c *Capture) bindSession(response SessionResponse) (*Session, error) {
+	session, err := response.Session()
+	if err != nil {
+		return nil, err
+	}
+	session.client = c
+	return session, nil
+}
+
+func remarshal(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// AttachSession returns a handle for an existing session without contacting
+// the API. Only ID is populated; call Refresh to load the rest.
+func (c *Capture) AttachSession(sessionID string) *Session {
+	return &Session{ID: sessionID, client: c}
+}
+
+func (c *Capture) CreateSessionTyped(options *CreateSessionOptions) (*Session, error) {
+	return c.CreateSessionTypedContext(context.Background(), options)
+}
+
+// CreateSessionTypedContext is like CreateSessionTyped but aborts the request
+// when ctx is canceled or its deadline expires.
+func (c *Capture) CreateSessionTypedContext(ctx context.Context, options *CreateSessionOptions) (*Session, error) {
+	response, err := c.CreateSessionContext(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return c.bindSession(response)
+}
+
+func (c *Capture) GetSessionTyped(sessionID string) (*Session, error) {
+	return c.GetSessionTypedContext(context.Background(), sessionID)
+}
+
+// GetSessionTypedContext is like GetSessionTyped but aborts the request when
+// ctx is canceled or its deadline expires.
+func (c *Capture) GetSessionTypedContext(ctx context.Context, sessionID string) (*Session, error) {
+	response, err := c.GetSessionContext(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return c.bindSession(response)
+}
+
+func (c *Capture) CloseSessionTyped(sessionID string) (*Session, error) {
+	return c.CloseSessionTypedContext(context.Background(), sessionID)
+}
+
+// CloseSessionTypedContext is like CloseSessionTyped but aborts the request
+// when ctx is canceled or its deadline expires. The returned session is nil
+// when the API does not echo the closed session.
+func (c *Capture) CloseSessionTypedContext(ctx context.Context, sessionID string) (*Session, error) {
+	response, err := c.CloseSessionContext(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := response["session"]; !ok {
+		return nil, nil
+	}
+	session, err := c.bindSession(response)
+	if err != nil {
+		return nil, err
+	}
+	session.closed = true
+	return session, nil
+}
+
+func (c *Capture) ExecuteActionTyped(sessionID, actionType string, payload SessionActionPayload) (*ActionResult, error) {
+	return c.ExecuteActionTypedContext(context.Background(), sessionID, actionType, payload)
+}
+
+// ExecuteActionTypedContext is like ExecuteActionTyped but aborts the request
+// when ctx is canceled or its deadline expires.
+func (c *Capture) ExecuteActionTypedContext(ctx context.Context, sessionID, actionType string, payload SessionActionPayload) (*ActionResult, error) {
+	response, err := c.ExecuteActionContext(ctx, sessionID, actionType, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ActionResult
+	if err := response.Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Action types understood by the Sessions API.
+const (
+	ActionGoto            = "goto"
+	ActionClick           = "click"
+	ActionType            = "type"
+	ActionWaitForSelector = "waitForSelector"
+	ActionEvaluate        = "evaluate"
+	ActionScreenshot      = "screenshot"
+	ActionGetCookies      = "getCookies"
+	ActionSetCookies      = "setCookies"
+)
+
+// GotoAction navigates the session to URL.
+type GotoAction struct {
+	URL string
+	// WaitUntil is the navigation event to wait for, e.g. "load" or
+	// "networkidle".
+	WaitUntil string
+	TimeoutMs int
+}
+
+func (a GotoAction) Payload() SessionActionPayload {
+	payload := SessionActionPayload{"url": a.URL}
+	setPayloadString(payload, "waitUntil", a.WaitUntil)
+	setPayloadInt(payload, "timeoutMs", a.TimeoutMs)
+	return payload
+}
+
+// ClickAction clicks the first element matching Selector.
+type ClickAction struct {
+	Selector string
+	// Button is "left", "right" or "middle".
+	Button     string
+	ClickCount int
+	TimeoutMs  int
+}
+
+func (a ClickAction) Payload() SessionActionPayload {
+	payload := SessionActionPayload{"selector": a.Selector}
+	setPayloadString(payload, "button", a.Button)
+	setPayloadInt(payload, "clickCount", a.ClickCount)
+	setPayloadInt(payload, "timeoutMs", a.TimeoutMs)
+	return payload
+}
+
+// TypeAction types Text into the element matching Selector.
+type TypeAction struct {
+	Selector string
+	Text     string
+	// DelayMs is the pause between key presses.
+	DelayMs   int
+	TimeoutMs int
+}
+
+func (a TypeAction) Payload() SessionActionPayload {
+	payload := SessionActionPayload{"selector": a.Selector, "text": a.Text}
+	setPayloadInt(payload, "delayMs", a.DelayMs)
+	setPayloadInt(payload, "timeoutMs", a.TimeoutMs)
+	return payload
+}
+
+// WaitForSelectorAction waits until an element matching Selector exists.
+type WaitForSelectorAction struct {
+	Selector    string
+	TimeoutMs   int
+	VisibleOnly bool
+}
+
+func (a WaitForSelectorAction) Payload() SessionActionPayload {
+	payload := SessionActionPayload{"selector": a.Selector}
+	setPayloadInt(payload, "timeoutMs", a.TimeoutMs)
+	if a.VisibleOnly {
+		payload["visibleOnly"] = true
+	}
+	return payload
+}
+
+// ScreenshotAction captures the current page, or a single element when
+// Selector is set.
+type ScreenshotAction struct {
+	FullPage bool
+	Selector string
+	Format   ImageFormat
+}
+
+func (a ScreenshotAction) Payload() SessionActionPayload {
+	payload := SessionActionPayload{}
+	if a.FullPage {
+		payload["fullPage"] = true
+	}
+	setPayloadString(payload, "selector", a.Selector)
+	setPayloadString(payload, "type", string(a.Format))
+	return payload
+}
+
+func setPayloadString(payload SessionActionPayload, key, value string) {
+	if value != "" {
+		payload[key] = value
+	}
+}
+
+func setPayloadInt(payload SessionActionPayload, key string, value int) {
+	if value != 0 {
+		payload[key] = value
+	}
+}
+
+func (s *Session) checkHandle() error {
+	if s.client == nil {
+		return fmt.Errorf("session %s is not bound to a client", s.ID)
+	}
+	if s.closed {
+		return fmt.Errorf("session %s is closed", s.ID)
+	}
+	return nil
+}
+
+// Do executes an arbitrary action in the session.
+func (s *Session) Do(ctx context.Context, actionType string, payload SessionActionPayload) (SessionActionResponse, error) {
+	if err := s.checkHandle(); err != nil {
+		return nil, err
+	}
+	return s.client.ExecuteActionContext(ctx, s.ID, actionType, payload)
+}
+
+func (s *Session) do(ctx context.Context, actionType string, payload SessionActionPayload, out interface{}) error {
+	response, err := s.Do(ctx, actionType, payload)
+	if err != nil {
+		return err
+	}
+	return response.Decode(out)
+}
+
+func (s *Session) Goto(ctx context.Context, action GotoAction) (*ActionResult, error) {
+	var result ActionResult
+	if err := s.do(ctx, ActionGoto, action.Payload(), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *Session) Click(ctx context.Context, action ClickAction) (*ActionResult, error) {
+	var result ActionResult
+	if err := s.do(ctx, ActionClick, action.Payload(), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *Session) Type(ctx context.Context, action TypeAction) (*ActionResult, error) {
+	var result ActionResult
+	if err := s.do(ctx, ActionType, action.Payload(), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *Session) WaitForSelector(ctx context.Context, action WaitForSelectorAction) (*ActionResult, error) {
+	var result ActionResult
+	if err := s.do(ctx, ActionWaitForSelector, action.Payload(), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Evaluate runs a JavaScript expression in the page and returns its value.
+func (s *Session) Evaluate(ctx context.Context, expression string) (*EvaluateResult, error) {
+	var result EvaluateResult
+	if err := s.do(ctx, ActionEvaluate, SessionActionPayload{"expression": expression}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *Session) Screenshot(ctx context.Context, action ScreenshotAction) (*ScreenshotResult, error) {
+	var result ScreenshotResult
+	if err := s.do(ctx, ActionScreenshot, action.Payload(), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Refresh reloads the session metadata from the API.
+func (s *Session) Refresh(ctx context.Context) error {
+	if s.client == nil {
+		return fmt.Errorf("session %s is not bound to a client", s.ID)
+	}
+	response, err := s.client.GetSessionContext(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+	updated, err := response.Session()
+	if err != nil {
+		return err
+	}
+
+	updated.client, updated.closed = s.client, s.closed
+	*s = *updated
+	return nil
+}
+
+// Close closes the session. It is safe to call more than once, which makes
+// it suitable for defer right after the session is created.
+func (s *Session) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext is like Close but aborts the request when ctx is canceled or
+// its deadline expires.
+func (s *Session) CloseContext(ctx context.Context) error {
+	if s.client == nil || s.closed {
+		return nil
+	}
+	if _, err := s.client.CloseSessionContext(ctx, s.ID); err != nil {
+		return err
+	}
+	s.closed = true
+	return nil
+}
@@ -0,0 +1,234 @@
+package capture
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSessionResponseSession(t *testing.T) {
+	response := SessionResponse{
+		"success": true,
+		"session": map[string]interface{}{
+			"id":            "sess_123",
+			"status":        "active",
+			"connectUrl":    "wss://connect.capture.page/sess_123",
+			"cdp":           true,
+			"maxTtlSeconds": float64(300),
+			"startedAt":     "2026-06-07T00:00:00Z",
+			"expiresAt":     "2026-06-07T00:05:00Z",
+			"billedCredits": nil,
+			"options":       map[string]interface{}{"cdp": true},
+			"futureField":   "kept",
+		},
+	}
+
+	session, err := response.Session()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if session.ID != "sess_123" || session.Status != "active" || !session.CDP || session.MaxTtlSeconds != 300 {
+		t.Fatalf("unexpected session: %#v", session)
+	}
+	if session.ConnectURL != "wss://connect.capture.page/sess_123" {
+		t.Fatalf("unexpected connect URL: %s", session.ConnectURL)
+	}
+	if !session.ExpiresAt.Equal(time.Date(2026, 6, 7, 0, 5, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected expiresAt: %v", session.ExpiresAt)
+	}
+	if session.Raw["futureField"] != "kept" || session.Options["cdp"] != true {
+		t.Fatalf("expected raw fields to be kept, got %#v", session.Raw)
+	}
+}
+
+func TestSessionResponseSessionMissing(t *testing.T) {
+	if _, err := (SessionResponse{"success": true}).Session(); err == nil {
+		t.Fatal("expected error for response without a session")
+	}
+}
+
+func TestSessionActionResponseDecode(t *testing.T) {
+	image := []byte("png-bytes")
+	response := SessionActionResponse{
+		"success":     true,
+		"data":        base64.StdEncoding.EncodeToString(image),
+		"contentType": "image/png",
+	}
+
+	var screenshot ScreenshotResult
+	if err := response.Decode(&screenshot); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !screenshot.Success || screenshot.ContentType != "image/png" || screenshot.Raw == nil {
+		t.Fatalf("unexpected result: %#v", screenshot)
+	}
+	decoded, err := screenshot.Image()
+	if err != nil || string(decoded) != "png-bytes" {
+		t.Fatalf("Image() = %q, %v", decoded, err)
+	}
+
+	var evaluate EvaluateResult
+	if err := (SessionActionResponse{"success": true, "result": float64(42)}).Decode(&evaluate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if evaluate.Result != float64(42) {
+		t.Fatalf("unexpected evaluate result: %#v", evaluate)
+	}
+}
+
+func TestTypedSessionMethods(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/sessions/sess_123/actions":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"url":     "https://example.com",
+				"title":   "Example Domain",
+			})
+		case r.Method == http.MethodDelete:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"session": map[string]interface{}{"id": "sess_123", "status": "active"},
+			})
+		}
+	}))
+	defer server.Close()
+
+	c := New("user_123", "secret")
+	c.SessionsURL = server.URL
+
+	session, err := c.CreateSessionTyped(&CreateSessionOptions{MaxTtlSeconds: 60})
+	if err != nil || session.ID != "sess_123" {
+		t.Fatalf("CreateSessionTyped() = %#v, %v", session, err)
+	}
+	session, err = c.GetSessionTyped("sess_123")
+	if err != nil || session.Status != "active" {
+		t.Fatalf("GetSessionTyped() = %#v, %v", session, err)
+	}
+	result, err := c.ExecuteActionTyped("sess_123", "goto", SessionActionPayload{"url": "https://example.com"})
+	if err != nil || !result.Success || result.Title != "Example Domain" || result.Raw["url"] != "https://example.com" {
+		t.Fatalf("ExecuteActionTyped() = %#v, %v", result, err)
+	}
+	closed, err := c.CloseSessionTyped("sess_123")
+	if err != nil || closed != nil {
+		t.Fatalf("CloseSessionTyped() = %#v, %v", closed, err)
+	}
+}
+
+func TestSessionHandle(t *testing.T) {
+	var actions []map[string]interface{}
+	var closes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/sessions/sess_123/actions":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			actions = append(actions, body)
+			response := map[string]interface{}{"success": true}
+			switch body["type"] {
+			case ActionEvaluate:
+				response["result"] = "Example Domain"
+			case ActionScreenshot:
+				response["data"] = base64.StdEncoding.EncodeToString([]byte("png"))
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		case r.Method == http.MethodDelete:
+			closes++
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		case r.Method == http.MethodPost:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"session": map[string]interface{}{"id": "sess_123", "status": "active"},
+			})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"session": map[string]interface{}{"id": "sess_123", "status": "active", "actionCount": 5},
+			})
+		}
+	}))
+	defer server.Close()
+
+	c := New("user_123", "secret")
+	c.SessionsURL = server.URL
+	ctx := context.Background()
+
+	sess, err := c.CreateSessionTyped(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer sess.Close()
+
+	if _, err := sess.Goto(ctx, GotoAction{URL: "https://example.com", WaitUntil: "load"}); err != nil {
+		t.Fatalf("Goto() error: %v", err)
+	}
+	if _, err := sess.Click(ctx, ClickAction{Selector: "a"}); err != nil {
+		t.Fatalf("Click() error: %v", err)
+	}
+	if _, err := sess.Type(ctx, TypeAction{Selector: "input", Text: "hello", DelayMs: 10}); err != nil {
+		t.Fatalf("Type() error: %v", err)
+	}
+	if _, err := sess.WaitForSelector(ctx, WaitForSelectorAction{Selector: "h1", TimeoutMs: 1000, VisibleOnly: true}); err != nil {
+		t.Fatalf("WaitForSelector() error: %v", err)
+	}
+	evaluated, err := sess.Evaluate(ctx, "document.title")
+	if err != nil || evaluated.Result != "Example Domain" {
+		t.Fatalf("Evaluate() = %#v, %v", evaluated, err)
+	}
+	shot, err := sess.Screenshot(ctx, ScreenshotAction{FullPage: true})
+	if err != nil {
+		t.Fatalf("Screenshot() error: %v", err)
+	}
+	if image, err := shot.Image(); err != nil || string(image) != "png" {
+		t.Fatalf("Image() = %q, %v", image, err)
+	}
+	if err := sess.Refresh(ctx); err != nil || sess.ActionCount != 5 {
+		t.Fatalf("Refresh() = %#v, %v", sess, err)
+	}
+
+	wantTypes := []string{ActionGoto, ActionClick, ActionType, ActionWaitForSelector, ActionEvaluate, ActionScreenshot}
+	if len(actions) != len(wantTypes) {
+		t.Fatalf("got %d actions, want %d", len(actions), len(wantTypes))
+	}
+	for i, want := range wantTypes {
+		if actions[i]["type"] != want {
+			t.Errorf("action %d type = %v, want %s", i, actions[i]["type"], want)
+		}
+	}
+	gotoPayload := actions[0]["payload"].(map[string]interface{})
+	if gotoPayload["url"] != "https://example.com" || gotoPayload["waitUntil"] != "load" {
+		t.Errorf("unexpected goto payload: %#v", gotoPayload)
+	}
+	waitPayload := actions[3]["payload"].(map[string]interface{})
+	if waitPayload["visibleOnly"] != true || waitPayload["timeoutMs"] != float64(1000) {
+		t.Errorf("unexpected waitForSelector payload: %#v", waitPayload)
+	}
+
+	if err := sess.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if err := sess.Close(); err != nil {
+		t.Fatalf("second Close() error: %v", err)
+	}
+	if closes != 1 {
+		t.Fatalf("expected 1 close request, got %d", closes)
+	}
+	if _, err := sess.Goto(ctx, GotoAction{URL: "https://example.com"}); err == nil {
+		t.Fatal("expected error when using a closed session")
+	}
+}
+
+func TestAttachSession(t *testing.T) {
+	c := New("user_123", "secret")
+	sess := c.AttachSession("sess_456")
+	if sess.ID != "sess_456" || sess.client == nil {
+		t.Fatalf("unexpected attached session: %#v", sess)
+	}
+	if _, err := (&Session{ID: "sess_789"}).Goto(context.Background(), GotoAction{URL: "https://example.com"}); err == nil {
+		t.Fatal("expected error for unbound session")
+	}
+}
@@ -0,0 +1,254 @@
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Cookie is a browser cookie as exchanged with the getCookies and setCookies
+// actions. Expires is in Unix seconds; -1 marks a session cookie.
+type Cookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"`
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	// SameSite is "Strict", "Lax" or "None".
+	SameSite string `json:"sameSite,omitempty"`
+}
+
+// StorageItem is a single localStorage entry.
+type StorageItem struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// OriginStorage holds the localStorage of one origin.
+type OriginStorage struct {
+	Origin       string        `json:"origin"`
+	LocalStorage []StorageItem `json:"localStorage"`
+}
+
+// StorageState is a snapshot of a session's cookies and localStorage. Its
+// JSON form matches Playwright's storage state files.
+type StorageState struct {
+	Cookies []Cookie        `json:"cookies"`
+	Origins []OriginStorage `json:"origins"`
+}
+
+// Cookies returns every cookie in the session's browser.
+func (s *Session) Cookies(ctx context.Context) ([]Cookie, error) {
+	var result struct {
+		ActionResult
+		Cookies []Cookie `json:"cookies"`
+	}
+	if err := s.do(ctx, ActionGetCookies, SessionActionPayload{}, &result); err != nil {
+		return nil, err
+	}
+	if result.Cookies == nil {
+		return []Cookie{}, nil
+	}
+	return result.Cookies, nil
+}
+
+// SetCookies adds cookies to the session's browser. They apply to any page
+// loaded afterwards, so restore them before navigating.
+func (s *Session) SetCookies(ctx context.Context, cookies []Cookie) error {
+	if len(cookies) == 0 {
+		return nil
+	}
+	var payloadCookies []interface{}
+	if err := remarshal(cookies, &payloadCookies); err != nil {
+		return fmt.Errorf("failed to encode cookies: %w", err)
+	}
+	_, err := s.Do(ctx, ActionSetCookies, SessionActionPayload{"cookies": payloadCookies})
+	return err
+}
+
+var errNoOrigin = errors.New("the current page has no origin; navigate to a site first")
+
+const localStorageScript = `JSON.stringify({origin: location.origin, localStorage: Object.keys(localStorage).map(function (name) { return {name: name, value: localStorage.getItem(name)}; })})`
+
+// LocalStorage returns the localStorage of the page currently loaded in the
+// session.
+func (s *Session) LocalStorage(ctx context.Context) (*OriginStorage, error) {
+	result, err := s.Evaluate(ctx, localStorageScript)
+	if err != nil {
+		return nil, err
+	}
+	encoded, ok := result.Result.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected localStorage result: %v", result.Result)
+	}
+
+	var storage OriginStorage
+	if err := json.Unmarshal([]byte(encoded), &storage); err != nil {
+		return nil, fmt.Errorf("failed to decode localStorage: %w", err)
+	}
+	if storage.Origin == "null" || storage.Origin == "" {
+		return nil, errNoOrigin
+	}
+	if storage.LocalStorage == nil {
+		storage.LocalStorage = []StorageItem{}
+	}
+	return &storage, nil
+}
+
+// SetLocalStorage writes items into the localStorage of storage.Origin. The
+// session navigates to the origin first, since localStorage can only be
+// written by a page of the same origin.
+func (s *Session) SetLocalStorage(ctx context.Context, storage OriginStorage) error {
+	if len(storage.LocalStorage) == 0 {
+		return nil
+	}
+	origin, err := url.Parse(storage.Origin)
+	if err != nil || origin.Scheme == "" || origin.Host == "" {
+		return fmt.Errorf("invalid origin %q", storage.Origin)
+	}
+
+	if _, err := s.Goto(ctx, GotoAction{URL: origin.Scheme + "://" + origin.Host + "/"}); err != nil {
+		return err
+	}
+
+	items, err := json.Marshal(storage.LocalStorage)
+	if err != nil {
+		return fmt.Errorf("failed to encode localStorage: %w", err)
+	}
+	script := "(function (items) { items.forEach(function (item) { localStorage.setItem(item.name, item.value); }); return items.length; })(" + string(items) + ")"
+	_, err = s.Evaluate(ctx, script)
+	return err
+}
+
+// StorageState exports the session's cookies and the localStorage of the
+// page currently loaded. Origins is empty when no page is loaded.
+func (s *Session) StorageState(ctx context.Context) (*StorageState, error) {
+	cookies, err := s.Cookies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &StorageState{Cookies: cookies, Origins: []OriginStorage{}}
+	storage, err := s.LocalStorage(ctx)
+	switch {
+	case errors.Is(err, errNoOrigin):
+	case err != nil:
+		return nil, err
+	case len(storage.LocalStorage) > 0:
+		state.Origins = append(state.Origins, *storage)
+	}
+	return state, nil
+}
+
+// RestoreStorageState sets the cookies of state and writes each origin's
+// localStorage. Call it on a fresh session before navigating; restoring
+// localStorage leaves the session on the last origin's root page.
+func (s *Session) RestoreStorageState(ctx context.Context, state *StorageState) error {
+	if err := s.SetCookies(ctx, state.Cookies); err != nil {
+		return err
+	}
+	for _, storage := range state.Origins {
+		if err := s.SetLocalStorage(ctx, storage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const netscapeHeader = "# Netscape HTTP Cookie File"
+
+// WriteNetscapeCookies writes cookies in the Netscape cookies.txt format
+// understood by curl and wget.
+func WriteNetscapeCookies(w io.Writer, cookies []Cookie) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, netscapeHeader)
+	for _, cookie := range cookies {
+		domain := cookie.Domain
+		if cookie.HTTPOnly {
+			domain = "#HttpOnly_" + domain
+		}
+		expires := int64(0)
+		if cookie.Expires > 0 {
+			expires = int64(cookie.Expires)
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain,
+			netscapeBool(strings.HasPrefix(cookie.Domain, ".")),
+			cookiePath(cookie.Path),
+			netscapeBool(cookie.Secure),
+			expires,
+			cookie.Name,
+			cookie.Value,
+		)
+	}
+	return bw.Flush()
+}
+
+// ReadNetscapeCookies parses a Netscape cookies.txt file.
+func ReadNetscapeCookies(r io.Reader) ([]Cookie, error) {
+	var cookies []Cookie
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := false
+		if strings.HasPrefix(text, "#HttpOnly_") {
+			text = strings.TrimPrefix(text, "#HttpOnly_")
+			httpOnly = true
+		}
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("invalid cookie on line %d: expected 7 tab-separated fields, got %d", line, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie expiry on line %d: %w", line, err)
+		}
+
+		cookie := Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Domain:   fields[0],
+			Path:     fields[2],
+			Expires:  float64(expires),
+			HTTPOnly: httpOnly,
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+		}
+		if expires == 0 {
+			cookie.Expires = -1
+		}
+		cookies = append(cookies, cookie)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookies: %w", err)
+	}
+	if cookies == nil {
+		cookies = []Cookie{}
+	}
+	return cookies, nil
+}
+
+func netscapeBool(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func cookiePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
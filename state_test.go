@@ -0,0 +1,108 @@
+package capture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSessionStorageStateRoundTrip(t *testing.T) {
+	var (
+		actions []string
+		stored  []interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Type    string                 `json:"type"`
+			Payload map[string]interface{} `json:"payload"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		actions = append(actions, body.Type)
+
+		response := map[string]interface{}{"success": true}
+		switch body.Type {
+		case ActionGetCookies:
+			response["cookies"] = []map[string]interface{}{
+				{"name": "sid", "value": "abc", "domain": ".example.com", "path": "/", "expires": -1, "httpOnly": true, "secure": true, "sameSite": "Lax"},
+			}
+		case ActionSetCookies:
+			stored = body.Payload["cookies"].([]interface{})
+		case ActionGoto:
+			if body.Payload["url"] != "https://app.example.com/" {
+				t.Errorf("unexpected goto: %v", body.Payload["url"])
+			}
+		case ActionEvaluate:
+			expression := body.Payload["expression"].(string)
+			if strings.HasPrefix(expression, "JSON.stringify") {
+				response["result"] = `{"origin":"https://app.example.com","localStorage":[{"name":"token","value":"t1"}]}`
+			} else if !strings.Contains(expression, `"name":"token"`) {
+				t.Errorf("unexpected restore script: %s", expression)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	c := New("key", "secret")
+	c.SessionsURL = server.URL
+	session := c.AttachSession("sess_1")
+	ctx := context.Background()
+
+	state, err := session.StorageState(ctx)
+	if err != nil {
+		t.Fatalf("StorageState() error: %v", err)
+	}
+	want := &StorageState{
+		Cookies: []Cookie{{Name: "sid", Value: "abc", Domain: ".example.com", Path: "/", Expires: -1, HTTPOnly: true, Secure: true, SameSite: "Lax"}},
+		Origins: []OriginStorage{{Origin: "https://app.example.com", LocalStorage: []StorageItem{{Name: "token", Value: "t1"}}}},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Fatalf("StorageState() = %#v, want %#v", state, want)
+	}
+
+	if err := c.AttachSession("sess_2").RestoreStorageState(ctx, state); err != nil {
+		t.Fatalf("RestoreStorageState() error: %v", err)
+	}
+	if got := strings.Join(actions, ","); got != "getCookies,evaluate,setCookies,goto,evaluate" {
+		t.Fatalf("unexpected actions: %s", got)
+	}
+	if len(stored) != 1 || stored[0].(map[string]interface{})["name"] != "sid" {
+		t.Fatalf("unexpected restored cookies: %#v", stored)
+	}
+}
+
+func TestNetscapeCookies(t *testing.T) {
+	cookies := []Cookie{
+		{Name: "sid", Value: "abc", Domain: ".example.com", Path: "/", Expires: 1893456000, HTTPOnly: true, Secure: true},
+		{Name: "pref", Value: "dark", Domain: "app.example.com", Path: "", Expires: -1},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNetscapeCookies(&buf, cookies); err != nil {
+		t.Fatalf("WriteNetscapeCookies() error: %v", err)
+	}
+	wantText := "# Netscape HTTP Cookie File\n" +
+		"#HttpOnly_.example.com\tTRUE\t/\tTRUE\t1893456000\tsid\tabc\n" +
+		"app.example.com\tFALSE\t/\tFALSE\t0\tpref\tdark\n"
+	if buf.String() != wantText {
+		t.Fatalf("unexpected cookies.txt:\n%s", buf.String())
+	}
+
+	parsed, err := ReadNetscapeCookies(&buf)
+	if err != nil {
+		t.Fatalf("ReadNetscapeCookies() error: %v", err)
+	}
+	cookies[1].Path = "/"
+	if !reflect.DeepEqual(parsed, cookies) {
+		t.Fatalf("ReadNetscapeCookies() = %#v, want %#v", parsed, cookies)
+	}
+
+	if _, err := ReadNetscapeCookies(strings.NewReader("example.com\tFALSE\t/\n")); err == nil {
+		t.Fatal("expected error for a malformed line")
+	}
+}
@@ -0,0 +1,58 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// fetchTo streams the response body for requestType into w without
+// buffering it, returning the number of bytes written.
+func (c *Capture) fetchTo(ctx context.Context, w io.Writer, requestType RequestType, targetURL string, options RequestOptions) (int64, error) {
+	body, err := c.open(ctx, requestType, targetURL, options)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return n, fmt.Errorf("failed to stream response body: %w", err)
+	}
+
+	return n, nil
+}
+
+// FetchImageTo writes the rendered image to w as it is received, which keeps
+// memory usage flat for large full-page captures.
+func (c *Capture) FetchImageTo(w io.Writer, targetURL string, options RequestOptions) (int64, error) {
+	return c.FetchImageToContext(context.Background(), w, targetURL, options)
+}
+
+// FetchImageToContext is like FetchImageTo but aborts the request when ctx is
+// canceled or its deadline expires.
+func (c *Capture) FetchImageToContext(ctx context.Context, w io.Writer, targetURL string, options RequestOptions) (int64, error) {
+	return c.fetchTo(ctx, w, RequestTypeImage, targetURL, options)
+}
+
+// FetchPDFTo writes the generated PDF to w as it is received.
+func (c *Capture) FetchPDFTo(w io.Writer, targetURL string, options RequestOptions) (int64, error) {
+	return c.FetchPDFToContext(context.Background(), w, targetURL, options)
+}
+
+// FetchPDFToContext is like FetchPDFTo but aborts the request when ctx is
+// canceled or its deadline expires.
+func (c *Capture) FetchPDFToContext(ctx context.Context, w io.Writer, targetURL string, options RequestOptions) (int64, error) {
+	return c.fetchTo(ctx, w, RequestTypePDF, targetURL, options)
+}
+
+// FetchAnimatedTo writes the animated recording to w as it is received.
+func (c *Capture) FetchAnimatedTo(w io.Writer, targetURL string, options RequestOptions) (int64, error) {
+	return c.FetchAnimatedToContext(context.Background(), w, targetURL, options)
+}
+
+// FetchAnimatedToContext is like FetchAnimatedTo but aborts the request when
+// ctx is canceled or its deadline expires.
+func (c *Capture) FetchAnimatedToContext(ctx context.Context, w io.Writer, targetURL string, options RequestOptions) (int64, error) {
+	return c.fetchTo(ctx, w, RequestTypeAnimated, targetURL, options)
+}
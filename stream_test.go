@@ -0,0 +1,54 @@
+package capture
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchImageTo(t *testing.T) {
+	payload := strings.Repeat("x", 1<<20)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/image") {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret")
+	c.APIURL = server.URL
+
+	var buf bytes.Buffer
+	n, err := c.FetchImageTo(&buf, "https://example.com", RequestOptions{"full": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != int64(len(payload)) || buf.String() != payload {
+		t.Fatalf("wrote %d bytes, want %d", n, len(payload))
+	}
+}
+
+func TestFetchPDFToError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid format"}`))
+	}))
+	defer server.Close()
+
+	c := New("test_key", "test_secret")
+	c.APIURL = server.URL
+
+	var buf bytes.Buffer
+	_, err := c.FetchPDFTo(&buf, "https://example.com", nil)
+	var apiErr *CaptureAPIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "invalid format" {
+		t.Fatalf("expected CaptureAPIError, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing written on error, got %q", buf.String())
+	}
+}